@@ -1,6 +1,9 @@
 package settingstore
 
 import (
+	"log/slog"
+	"strconv"
+
 	"github.com/dromara/carbon/v2"
 	"github.com/gouniverse/dataobject"
 	"github.com/gouniverse/sb"
@@ -12,6 +15,7 @@ var _ SettingInterface = (*Setting)(nil)
 // Setting type
 type Setting struct {
 	dataobject.DataObject
+	sensitive bool
 }
 
 // == CONSTRUCTORS ============================================================
@@ -19,6 +23,7 @@ type Setting struct {
 func NewSetting() SettingInterface {
 	createdAt := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
 	updatedAt := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+	expiresAt := sb.MAX_DATETIME
 	deletedAt := sb.MAX_DATETIME
 
 	o := (&Setting{})
@@ -28,7 +33,10 @@ func NewSetting() SettingInterface {
 		SetValue("").
 		SetCreatedAt(createdAt).
 		SetUpdatedAt(updatedAt).
-		SetSoftDeletedAt(deletedAt)
+		SetExpiresAt(expiresAt).
+		SetSoftDeletedAt(deletedAt).
+		SetTenantID("").
+		SetVersion(1)
 
 	return o
 }
@@ -41,10 +49,52 @@ func NewSettingFromExistingData(data map[string]string) SettingInterface {
 
 // == METHODS =================================================================
 
+// IsExpired returns true if the setting has an expiry time which is in the past.
+// Settings without an expiry time (legacy rows) never expire.
+func (o *Setting) IsExpired() bool {
+	if o.GetExpiresAt() == "" {
+		return false
+	}
+
+	return o.GetExpiresAtCarbon().Compare("<=", carbon.Now(carbon.UTC))
+}
+
 func (o *Setting) IsSoftDeleted() bool {
 	return o.GetSoftDeletedAtCarbon().Compare("<", carbon.Now(carbon.UTC))
 }
 
+// IsSensitive returns true if the value of the setting is sensitive,
+// and so masked in its log output
+func (o *Setting) IsSensitive() bool {
+	return o.sensitive
+}
+
+// SetSensitive marks the value of the setting as sensitive, or not.
+// The flag is not saved, the store sets it from its sensitive keys
+func (o *Setting) SetSensitive(sensitive bool) SettingInterface {
+	o.sensitive = sensitive
+	return o
+}
+
+// LogValue implements slog.LogValuer, so that the setting is logged
+// with its value masked if it is sensitive
+func (o *Setting) LogValue() slog.Value {
+	value := o.GetValue()
+
+	if o.sensitive && value != "" {
+		value = REDACTED_VALUE
+	}
+
+	return slog.GroupValue(
+		slog.String(COLUMN_ID, o.GetID()),
+		slog.String(COLUMN_TENANT_ID, o.GetTenantID()),
+		slog.String(COLUMN_SETTING_KEY, o.GetKey()),
+		slog.String(COLUMN_SETTING_VALUE, value),
+		slog.String(COLUMN_EXPIRES_AT, o.GetExpiresAt()),
+		slog.Int64(COLUMN_VERSION, o.GetVersion()),
+	)
+}
+
 // == SETTERS AND GETTERS =====================================================
 
 func (setting *Setting) GetID() string {
@@ -100,6 +150,19 @@ func (setting *Setting) SetUpdatedAt(updatedAt string) SettingInterface {
 	return setting
 }
 
+func (setting *Setting) GetExpiresAt() string {
+	return setting.Get(COLUMN_EXPIRES_AT)
+}
+
+func (setting *Setting) GetExpiresAtCarbon() *carbon.Carbon {
+	return carbon.Parse(setting.GetExpiresAt(), carbon.UTC)
+}
+
+func (setting *Setting) SetExpiresAt(expiresAt string) SettingInterface {
+	setting.Set(COLUMN_EXPIRES_AT, expiresAt)
+	return setting
+}
+
 func (setting *Setting) GetSoftDeletedAt() string {
 	return setting.Get(COLUMN_SOFT_DELETED_AT)
 }
@@ -112,3 +175,28 @@ func (setting *Setting) SetSoftDeletedAt(deletedAt string) SettingInterface {
 	setting.Set(COLUMN_SOFT_DELETED_AT, deletedAt)
 	return setting
 }
+
+// GetTenantID returns the ID of the tenant owning the setting,
+// empty for the settings which belong to no tenant
+func (setting *Setting) GetTenantID() string {
+	return setting.Get(COLUMN_TENANT_ID)
+}
+
+func (setting *Setting) SetTenantID(tenantID string) SettingInterface {
+	setting.Set(COLUMN_TENANT_ID, tenantID)
+	return setting
+}
+
+// GetVersion returns the version of the setting, which every update
+// increments. Zero if the version was not loaded
+func (setting *Setting) GetVersion() int64 {
+	version, _ := strconv.ParseInt(setting.Get(COLUMN_VERSION), 10, 64)
+	return version
+}
+
+// SetVersion sets the version the setting is expected to have in the
+// database, when it is updated
+func (setting *Setting) SetVersion(version int64) SettingInterface {
+	setting.Set(COLUMN_VERSION, strconv.FormatInt(version, 10))
+	return setting
+}
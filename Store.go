@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
 	"log/slog"
 	"strconv"
 	"strings"
@@ -15,10 +14,12 @@ import (
 	_ "github.com/doug-martin/goqu/v9/dialect/postgres"  // importing postgres dialect
 	_ "github.com/doug-martin/goqu/v9/dialect/sqlite3"   // importing sqlite3 dialect
 	_ "github.com/doug-martin/goqu/v9/dialect/sqlserver" // importing sqlserver dialect
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/dromara/carbon/v2"
 	"github.com/gouniverse/base/database"
 	"github.com/gouniverse/sb"
 	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
 )
 
 // == INTERFACE ===============================================================
@@ -36,11 +37,29 @@ type store struct {
 	automigrateEnabled bool
 	debugEnabled       bool
 	sqlLogger          *slog.Logger
+	revisionTableName  string
+	historyTableName   string
+	auditTableName     string
+	auditHMACKey       []byte
+	leaseTableName     string
+	encryption         *encryptionKeyring
+	sensitiveKeys      keyMatcher
+	flights            *singleflight.Group
+	sweeper            *sweeper
+	cache              *settingCache
+	revisions          *revisionTracker
+	transaction        *transaction
+	keyPrefix          string
+	tenantID           string
+	tenantScoped       bool
+	scopeErr           error
 }
 
 // PUBLIC METHODS ============================================================
 
-// AutoMigrate creates the settings table if it does not exist
+// AutoMigrate creates the settings table if it does not exist,
+// adds any columns missing from a table created by an older version,
+// and creates the unique index of the keys
 //
 // Parameters:
 // - ctx: the context
@@ -64,6 +83,36 @@ func (store *store) AutoMigrate(ctx context.Context) error {
 		return err
 	}
 
+	if err := store.migrateColumns(ctx, store.settingTableName, store.settingTableColumns(), store.settingTableColumnDefaults()); err != nil {
+		return err
+	}
+
+	if err := store.migrateKeyIndex(ctx); err != nil {
+		return err
+	}
+
+	if store.revisionTableName != "" {
+		if err := store.migrateRevisionTable(ctx); err != nil {
+			return err
+		}
+	}
+
+	if store.historyTableName != "" {
+		if err := store.migrateHistoryTable(ctx); err != nil {
+			return err
+		}
+	}
+
+	if store.auditTableName != "" {
+		if err := store.migrateAuditTable(ctx); err != nil {
+			return err
+		}
+	}
+
+	if store.leaseTableName != "" {
+		return store.migrateLeaseTable(ctx)
+	}
+
 	return nil
 }
 
@@ -94,10 +143,131 @@ func (st *store) Delete(ctx context.Context, settingKey string) error {
 	return st.SettingDeleteByKey(ctx, settingKey)
 }
 
+// DeleteByPrefix hard deletes the settings with keys starting with the prefix
+//
+// Parameters:
+// - ctx: the context
+// - keyPrefix: the prefix of the keys to delete, i.e. "mail.smtp."
+//
+// Returns:
+// - int64 - the number of settings deleted
+// - error - nil if no error, error otherwise
+func (st *store) DeleteByPrefix(ctx context.Context, keyPrefix string) (int64, error) {
+	if keyPrefix == "" {
+		return 0, errors.New("settingstore > delete by prefix. key prefix cannot be empty")
+	}
+
+	conditions := []exp.Expression{
+		keyPrefixExpression(st.namespacedKey(keyPrefix)),
+		st.tenantExpression(),
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(st.dbDriverName).
+		Delete(st.settingTableName).
+		Prepared(true).
+		Where(conditions...).
+		ToSQL()
+
+	if errSql != nil {
+		return 0, errSql
+	}
+
+	st.logSql("delete", sqlStr, params...)
+
+	return st.executeDeletion(ctx, conditions, HISTORY_ACTION_DELETE, sqlStr, params...)
+}
+
+// SoftDeleteByPrefix soft deletes the settings with keys starting with the prefix
+//
+// Parameters:
+// - ctx: the context
+// - keyPrefix: the prefix of the keys to soft delete, i.e. "mail.smtp."
+//
+// Returns:
+// - int64 - the number of settings soft deleted
+// - error - nil if no error, error otherwise
+func (st *store) SoftDeleteByPrefix(ctx context.Context, keyPrefix string) (int64, error) {
+	if keyPrefix == "" {
+		return 0, errors.New("settingstore > soft delete by prefix. key prefix cannot be empty")
+	}
+
+	now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+	conditions := []exp.Expression{
+		keyPrefixExpression(st.namespacedKey(keyPrefix)),
+		st.tenantExpression(),
+		goqu.C(COLUMN_SOFT_DELETED_AT).Gt(now),
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(st.dbDriverName).
+		Update(st.settingTableName).
+		Prepared(true).
+		Set(goqu.Record{
+			COLUMN_SOFT_DELETED_AT: now,
+			COLUMN_UPDATED_AT:      now,
+			COLUMN_VERSION:         versionIncrementExpression(),
+		}).
+		Where(conditions...).
+		ToSQL()
+
+	if errSql != nil {
+		return 0, errSql
+	}
+
+	st.logSql("update", sqlStr, params...)
+
+	return st.executeDeletion(ctx, conditions, HISTORY_ACTION_SOFT_DELETE, sqlStr, params...)
+}
+
+// executeDeletion executes a deletion affecting any number of settings,
+// i.e. a whole key prefix, and returns the number of settings affected
+func (st *store) executeDeletion(ctx context.Context, conditions []exp.Expression, action string, sqlStr string, params ...any) (int64, error) {
+	affected := int64(0)
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		rows, err := txStore.loadChangedRows(ctx, conditions...)
+
+		if err != nil {
+			return err
+		}
+
+		result, err := txStore.executeSql(ctx, sqlStr, params...)
+
+		if err != nil {
+			return err
+		}
+
+		affected, err = result.RowsAffected()
+
+		if err != nil || affected < 1 {
+			return err
+		}
+
+		if err := txStore.recordDeletion(ctx, rows, action); err != nil {
+			return err
+		}
+
+		// the affected keys are not known, so the whole cache goes
+		txStore.afterCommit(func() {
+			if txStore.cache != nil {
+				txStore.cache.invalidateAll()
+			}
+		})
+
+		return txStore.revisionBump(ctx)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, nil
+}
+
 // Get is a shortcut method to get a value by key, or a default, if not found
 //
 // It is a convenience method which wraps SettingFindByKey and returns
-// the value directly
+// the value directly. If the cache is enabled, the value is read through it
 //
 // Parameters:
 // - ctx: the context
@@ -108,14 +278,14 @@ func (st *store) Delete(ctx context.Context, settingKey string) error {
 // - string - the value of the setting, or the default value if not found
 // - error - nil if no error, error otherwise
 func (st *store) Get(ctx context.Context, settingKey string, valueDefault string) (string, error) {
-	setting, errFindByKey := st.SettingFindByKey(ctx, settingKey)
+	value, found, errFindByKey := st.findValueByKey(ctx, settingKey)
 
 	if errFindByKey != nil {
 		return "", errFindByKey
 	}
 
-	if setting != nil {
-		return setting.GetValue(), nil
+	if found {
+		return value, nil
 	}
 
 	return valueDefault, nil
@@ -134,14 +304,13 @@ func (st *store) Get(ctx context.Context, settingKey string, valueDefault string
 // Returns:
 // - interface{}, error
 func (st *store) GetAny(ctx context.Context, key string, valueDefault any) (any, error) {
-	setting, errFindByKey := st.SettingFindByKey(ctx, key)
+	jsonValue, found, errFindByKey := st.findValueByKey(ctx, key)
 
 	if errFindByKey != nil {
 		return valueDefault, errFindByKey
 	}
 
-	if setting != nil {
-		jsonValue := setting.GetValue()
+	if found {
 		var val interface{}
 		jsonError := json.Unmarshal([]byte(jsonValue), &val)
 		if jsonError != nil {
@@ -168,14 +337,13 @@ func (st *store) GetAny(ctx context.Context, key string, valueDefault any) (any,
 // - map[string]any - the value of the setting, or the default value if not found
 // - error - nil if no error, error otherwise
 func (st *store) GetMap(ctx context.Context, key string, valueDefault map[string]any) (map[string]any, error) {
-	setting, errFindByKey := st.SettingFindByKey(ctx, key)
+	jsonValue, found, errFindByKey := st.findValueByKey(ctx, key)
 
 	if errFindByKey != nil {
 		return valueDefault, errFindByKey
 	}
 
-	if setting != nil {
-		jsonValue := setting.GetValue()
+	if found {
 		var val map[string]any
 		jsonError := json.Unmarshal([]byte(jsonValue), &val)
 		if jsonError != nil {
@@ -266,12 +434,10 @@ func (store *store) SettingCount(ctx context.Context, options SettingQueryInterf
 		return -1, errSql
 	}
 
-	if store.debugEnabled {
-		log.Println(sqlStr)
-	}
+	store.logSql("count", sqlStr, params...)
+
+	mapped, err := store.selectToMapString(ctx, sqlStr, params...)
 
-	db := sb.NewDatabase(store.db, store.dbDriverName)
-	mapped, err := db.SelectToMapString(sqlStr, params...)
 	if err != nil {
 		return -1, err
 	}
@@ -309,11 +475,30 @@ func (st *store) SettingCreate(ctx context.Context, setting SettingInterface) er
 		setting.SetUpdatedAt(carbon.Now(carbon.UTC).ToDateTimeString())
 	}
 
+	if setting.GetExpiresAt() == "" {
+		setting.SetExpiresAt(sb.MAX_DATETIME)
+	}
+
 	if setting.GetSoftDeletedAt() == "" {
 		setting.SetSoftDeletedAt(sb.MAX_DATETIME)
 	}
 
-	data := setting.Data()
+	if setting.GetVersion() < 1 {
+		setting.SetVersion(1)
+	}
+
+	data := lo.Assign(setting.Data(), map[string]string{
+		COLUMN_SETTING_KEY: st.namespacedKey(setting.GetKey()),
+		COLUMN_TENANT_ID:   st.tenantID,
+	})
+
+	sealedValue, errSeal := st.sealValue(st.tenantID, data[COLUMN_SETTING_KEY], setting.GetValue())
+
+	if errSeal != nil {
+		return errSeal
+	}
+
+	data[COLUMN_SETTING_VALUE] = sealedValue
 
 	sqlStr, sqlParams, sqlErr := goqu.Dialect(st.dbDriverName).
 		Insert(st.settingTableName).
@@ -325,9 +510,31 @@ func (st *store) SettingCreate(ctx context.Context, setting SettingInterface) er
 		return sqlErr
 	}
 
-	st.logSql("create", sqlStr, sqlParams)
+	st.logSql("create", sqlStr, st.redactParams(sqlParams, data[COLUMN_SETTING_KEY], sealedValue)...)
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		if _, err := txStore.executeSql(ctx, sqlStr, sqlParams...); err != nil {
+			return err
+		}
+
+		errRecord := txStore.recordChanges(ctx, recordedChange{
+			settingID: setting.GetID(),
+			tenantID:  txStore.tenantID,
+			key:       txStore.namespacedKey(setting.GetKey()),
+			action:    HISTORY_ACTION_CREATE,
+			newValue:  sealedValue,
+		})
+
+		if errRecord != nil {
+			return errRecord
+		}
+
+		txStore.afterCommit(func() {
+			txStore.cacheInvalidateKey(setting.GetKey()) // the key may be cached as missing
+		})
 
-	_, err := st.db.Exec(sqlStr, sqlParams...)
+		return txStore.revisionBump(ctx)
+	})
 
 	if err != nil {
 		return err
@@ -348,49 +555,96 @@ func (store *store) SettingDelete(ctx context.Context, setting SettingInterface)
 }
 
 // SettingDeleteByID deletes a setting by id
-func (store *store) SettingDeleteByID(ctx context.Context, id string) error {
+func (st *store) SettingDeleteByID(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("setting id is empty")
 	}
 
-	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
-		Delete(store.settingTableName).
+	conditions := []exp.Expression{
+		goqu.C(COLUMN_ID).Eq(id),
+		st.tenantExpression(),
+		st.namespaceExpression(),
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(st.dbDriverName).
+		Delete(st.settingTableName).
 		Prepared(true).
-		Where(goqu.C(COLUMN_ID).Eq(id)).
+		Where(conditions...).
 		ToSQL()
 
 	if errSql != nil {
 		return errSql
 	}
 
-	store.logSql("delete", sqlStr, params...)
+	st.logSql("delete", sqlStr, params...)
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		rows, err := txStore.loadChangedRows(ctx, conditions...)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := txStore.executeSql(ctx, sqlStr, params...); err != nil {
+			return err
+		}
+
+		if err := txStore.recordDeletion(ctx, rows, HISTORY_ACTION_DELETE); err != nil {
+			return err
+		}
 
-	_, err := store.db.Exec(sqlStr, params...)
+		txStore.afterCommit(func() {
+			txStore.cacheInvalidateID(id)
+		})
 
-	return err
+		return txStore.revisionBump(ctx)
+	})
 }
 
 // SettingDeleteByID deletes a setting by id
-func (store *store) SettingDeleteByKey(ctx context.Context, settingKey string) error {
+func (st *store) SettingDeleteByKey(ctx context.Context, settingKey string) error {
 	if settingKey == "" {
 		return errors.New("setting id is empty")
 	}
 
-	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
-		Delete(store.settingTableName).
+	conditions := []exp.Expression{
+		goqu.C(COLUMN_SETTING_KEY).Eq(st.namespacedKey(settingKey)),
+		st.tenantExpression(),
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(st.dbDriverName).
+		Delete(st.settingTableName).
 		Prepared(true).
-		Where(goqu.C(COLUMN_SETTING_KEY).Eq(settingKey)).
+		Where(conditions...).
 		ToSQL()
 
 	if errSql != nil {
 		return errSql
 	}
 
-	store.logSql("delete", sqlStr, params...)
+	st.logSql("delete", sqlStr, params...)
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		rows, err := txStore.loadChangedRows(ctx, conditions...)
 
-	_, err := store.db.Exec(sqlStr, params...)
+		if err != nil {
+			return err
+		}
+
+		if _, err := txStore.executeSql(ctx, sqlStr, params...); err != nil {
+			return err
+		}
 
-	return err
+		if err := txStore.recordDeletion(ctx, rows, HISTORY_ACTION_DELETE); err != nil {
+			return err
+		}
+
+		txStore.afterCommit(func() {
+			txStore.cacheInvalidateKey(settingKey)
+		})
+
+		return txStore.revisionBump(ctx)
+	})
 }
 
 // SettingFindByID finds a setting by id
@@ -416,8 +670,19 @@ func (store *store) SettingFindByID(ctx context.Context, settingID string) (Sett
 	return nil, nil
 }
 
-// SettingFindByKey finds a setting by key
+// SettingFindByKey finds a setting by key, with its value masked if it is sensitive
 func (store *store) SettingFindByKey(ctx context.Context, settingKey string) (SettingInterface, error) {
+	setting, err := store.settingFindByKey(ctx, settingKey)
+
+	if err != nil || setting == nil {
+		return setting, err
+	}
+
+	return redactSetting(setting), nil
+}
+
+// settingFindByKey finds a setting by key, with its value unmasked
+func (store *store) settingFindByKey(ctx context.Context, settingKey string) (SettingInterface, error) {
 	if settingKey == "" {
 		return nil, errors.New("setting store > find by key: setting key is required")
 	}
@@ -426,7 +691,7 @@ func (store *store) SettingFindByKey(ctx context.Context, settingKey string) (Se
 		SetKey(settingKey).
 		SetLimit(1)
 
-	list, err := store.SettingList(ctx, query)
+	list, err := store.settingList(ctx, query)
 
 	if err != nil {
 		return nil, err
@@ -439,7 +704,23 @@ func (store *store) SettingFindByKey(ctx context.Context, settingKey string) (Se
 	return nil, nil
 }
 
+// SettingList lists the settings matching the query, with the values
+// of the sensitive settings masked
 func (store *store) SettingList(ctx context.Context, query SettingQueryInterface) ([]SettingInterface, error) {
+	list, err := store.settingList(ctx, query)
+
+	if err != nil {
+		return list, err
+	}
+
+	return lo.Map(list, func(setting SettingInterface, _ int) SettingInterface {
+		return redactSetting(setting)
+	}), nil
+}
+
+// settingList lists the settings matching the query, with the values
+// unmasked, and the sensitive settings marked as such
+func (store *store) settingList(ctx context.Context, query SettingQueryInterface) ([]SettingInterface, error) {
 	if query == nil {
 		return []SettingInterface{}, errors.New("at setting list > setting query is nil")
 	}
@@ -462,17 +743,7 @@ func (store *store) SettingList(ctx context.Context, query SettingQueryInterface
 
 	store.logSql("list", sqlStr, sqlParams...)
 
-	if store.db == nil {
-		return []SettingInterface{}, errors.New("settingstore: database is nil")
-	}
-
-	db := sb.NewDatabase(store.db, store.dbDriverName)
-
-	if db == nil {
-		return []SettingInterface{}, errors.New("settingstore: database is nil")
-	}
-
-	modelMaps, err := db.SelectToMapString(sqlStr, sqlParams...)
+	modelMaps, err := store.selectToMapString(ctx, sqlStr, sqlParams...)
 
 	if err != nil {
 		return []SettingInterface{}, err
@@ -480,10 +751,36 @@ func (store *store) SettingList(ctx context.Context, query SettingQueryInterface
 
 	list := []SettingInterface{}
 
-	lo.ForEach(modelMaps, func(modelMap map[string]string, index int) {
-		model := NewSettingFromExistingData(modelMap)
+	for _, modelMap := range modelMaps {
+		settingKey, keySelected := modelMap[COLUMN_SETTING_KEY]
+		value, valueSelected := modelMap[COLUMN_SETTING_VALUE]
+
+		if keySelected && valueSelected {
+			tenantID, tenantSelected := modelMap[COLUMN_TENANT_ID]
+
+			if !tenantSelected {
+				tenantID = store.tenantID
+			}
+
+			opened, err := store.openValue(tenantID, settingKey, value)
+
+			if err != nil {
+				return []SettingInterface{}, err
+			}
+
+			modelMap[COLUMN_SETTING_VALUE] = opened
+		}
+
+		if keySelected {
+			modelMap[COLUMN_SETTING_KEY] = store.namespaceStrip(settingKey)
+		}
+
+		// a value listed without its key may be the one of a sensitive setting
+		sensitive := lo.Ternary(keySelected, store.isSensitiveKey(settingKey), store.hasSensitiveKeys())
+
+		model := NewSettingFromExistingData(modelMap).SetSensitive(sensitive)
 		list = append(list, model)
-	})
+	}
 
 	return list, nil
 }
@@ -498,22 +795,38 @@ func (store *store) SettingSoftDelete(ctx context.Context, setting SettingInterf
 	return store.SettingUpdate(ctx, setting)
 }
 
-func (store *store) SettingSoftDeleteByID(ctx context.Context, id string) error {
-	setting, err := store.SettingFindByID(ctx, id)
+func (st *store) SettingSoftDeleteByID(ctx context.Context, id string) error {
+	return st.inTransaction(ctx, func(txStore *store) error {
+		setting, err := txStore.SettingFindByID(ctx, id)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	return store.SettingSoftDelete(ctx, setting)
+		return txStore.SettingSoftDelete(ctx, setting)
+	})
 }
 
-func (store *store) SettingUpdate(ctx context.Context, setting SettingInterface) error {
+// SettingUpdate updates the changed fields of a setting
+//
+// The update only applies if the setting still has the version it was
+// loaded with, and increments it. Otherwise the setting was changed or
+// deleted by someone else, and ErrConflict is returned, so that their
+// change is not overwritten. A setting loaded without its version is
+// updated regardless
+//
+// Parameters:
+// - ctx: the context
+// - setting: the setting to update
+//
+// Returns:
+// - error - nil if no error, ErrConflict on a concurrent change, error otherwise
+func (st *store) SettingUpdate(ctx context.Context, setting SettingInterface) error {
 	if setting == nil {
 		return errors.New("settingstore > setting update. setting cannot be nil")
 	}
 
-	if store.db == nil {
+	if st.db == nil {
 		return errors.New("settingstore > setting update. db cannot be nil")
 	}
 
@@ -525,7 +838,31 @@ func (store *store) SettingUpdate(ctx context.Context, setting SettingInterface)
 		return nil
 	}
 
-	delete(dataChanged, COLUMN_ID) // ID cannot be updated
+	delete(dataChanged, COLUMN_ID)        // ID cannot be updated
+	delete(dataChanged, COLUMN_TENANT_ID) // a setting cannot move to another tenant
+	delete(dataChanged, COLUMN_VERSION)   // the version is incremented below
+
+	_, valueChanged := dataChanged[COLUMN_SETTING_VALUE]
+	_, keyChanged := dataChanged[COLUMN_SETTING_KEY]
+
+	if keyChanged {
+		dataChanged[COLUMN_SETTING_KEY] = st.namespacedKey(setting.GetKey())
+	}
+
+	if keyChanged && !valueChanged && setting.IsSensitive() && setting.GetValue() == REDACTED_VALUE {
+		return errors.New("settingstore > setting update. the key of a masked setting cannot be changed, as its value is not known")
+	}
+
+	// a sealed value is bound to its key, so it is sealed again when the key changes
+	if valueChanged || keyChanged {
+		sealedValue, errSeal := st.sealValue(st.tenantID, st.namespacedKey(setting.GetKey()), setting.GetValue())
+
+		if errSeal != nil {
+			return errSeal
+		}
+
+		dataChanged[COLUMN_SETTING_VALUE] = sealedValue
+	}
 
 	// fields := map[string]interface{}{}
 	// fields[COLUMN_SETTING_VALUE] = setting.GetValue()
@@ -545,33 +882,88 @@ func (store *store) SettingUpdate(ctx context.Context, setting SettingInterface)
 	// 	wheres = append(wheres, goqu.C(COLUMN_USER_ID).Eq(options.UserID))
 	// }
 
-	sqlStr, sqlParams, sqlErr := goqu.Dialect(store.dbDriverName).
-		Update(store.settingTableName).
+	record := goqu.Record{COLUMN_VERSION: versionIncrementExpression()}
+
+	for column, value := range dataChanged {
+		record[column] = value
+	}
+
+	// the version the setting was loaded with, unless it was not selected
+	version := setting.GetVersion()
+	versionCondition := lo.Ternary[exp.Expression](version > 0, goqu.C(COLUMN_VERSION).Eq(version), goqu.And())
+
+	// the key may be the new one, so the row is matched by its ID
+	sqlStr, sqlParams, sqlErr := goqu.Dialect(st.dbDriverName).
+		Update(st.settingTableName).
 		Prepared(true).
-		Where(goqu.C(COLUMN_SETTING_KEY).Eq(setting.GetKey())).
 		Where(goqu.C(COLUMN_ID).Eq(setting.GetID())).
-		Set(dataChanged).
+		Where(st.tenantExpression()).
+		Where(st.namespaceExpression()).
+		Where(versionCondition).
+		Set(record).
 		ToSQL()
 
 	if sqlErr != nil {
 		return sqlErr
 	}
 
-	store.logSql("update", sqlStr, sqlParams...)
+	st.logSql("update", sqlStr, st.redactParams(sqlParams, st.namespacedKey(setting.GetKey()), dataChanged[COLUMN_SETTING_VALUE])...)
 
-	_, err := store.db.Exec(sqlStr, sqlParams...)
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		change, err := txStore.updateChange(ctx, setting, dataChanged)
+
+		if err != nil {
+			return err
+		}
+
+		result, err := txStore.executeSql(ctx, sqlStr, sqlParams...)
+
+		if err != nil {
+			return err
+		}
+
+		if version > 0 {
+			affected, err := result.RowsAffected()
+
+			if err != nil {
+				return err
+			}
+
+			if affected < 1 {
+				return ErrConflict
+			}
+		}
+
+		if change != nil {
+			if err := txStore.recordChanges(ctx, *change); err != nil {
+				return err
+			}
+		}
+
+		txStore.afterCommit(func() {
+			txStore.cacheInvalidateKey(setting.GetKey())
+			txStore.cacheInvalidateID(setting.GetID())
+		})
+
+		return txStore.revisionBump(ctx)
+	})
 
 	if err != nil {
 		return err
 	}
 
+	if version > 0 {
+		setting.SetVersion(version + 1) // ready for the next update
+	}
+
 	return nil
 }
 
 // Set is a shortcut method to save a value by key, use Get to extract
 //
-// It is a convenience method which wraps SettingFindByKey,
-// and then SettingCreate or SettingUpdate
+// The setting is created, or updated if it exists, with a single
+// statement, so concurrent saves of a new key do not duplicate it.
+// The saved setting never expires, use SetWithTTL for an expiring setting
 //
 // Parameters:
 // - ctx: the context
@@ -581,23 +973,29 @@ func (store *store) SettingUpdate(ctx context.Context, setting SettingInterface)
 // Returns:
 // - error - nil if no error, error otherwise
 func (st *store) Set(ctx context.Context, settingKey string, value string) error {
-	setting, errFindByKey := st.SettingFindByKey(ctx, settingKey)
+	return st.SetWithTTL(ctx, settingKey, value, 0)
+}
 
-	if errFindByKey != nil {
-		return errFindByKey
+// SetWithTTL is a shortcut method to save a value by key, which expires
+// after the specified number of seconds, use Get to extract
+//
+// Once expired the setting is treated as absent by Get, GetAny, GetMap,
+// Has and SettingList
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+// - seconds: the seconds until the setting expires, 0 or less never expires
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetWithTTL(ctx context.Context, settingKey string, value string, seconds int64) error {
+	if settingKey == "" {
+		return errors.New("settingstore > set. key cannot be empty")
 	}
 
-	if setting == nil {
-		newSetting := NewSetting().
-			SetKey(settingKey).
-			SetValue(value)
-
-		return st.SettingCreate(ctx, newSetting)
-	} else {
-		setting.SetValue(value)
-
-		return st.SettingUpdate(ctx, setting)
-	}
+	return st.upsert(ctx, map[string]string{settingKey: value}, expiresAtFromSeconds(seconds))
 }
 
 // SetAny is a shortcut method to save any value by key, use GetAny to extract
@@ -609,6 +1007,7 @@ func (st *store) Set(ctx context.Context, settingKey string, value string) error
 // - ctx: the context
 // - settingKey: the key of the setting to save
 // - value: the value to save
+// - seconds: the seconds until the setting expires, 0 or less never expires
 //
 // Returns:
 // - error - nil if no error, error otherwise
@@ -618,13 +1017,13 @@ func (st *store) SetAny(ctx context.Context, key string, value interface{}, seco
 		return jsonError
 	}
 
-	return st.Set(ctx, key, string(jsonValue))
+	return st.SetWithTTL(ctx, key, string(jsonValue), seconds)
 }
 
 // SetMap is a shortcut method to save a map by key, use GetMap to extract
 //
 // It is a convenience method which wraps SettingCreate or SettingUpdate
-// to save a map by key
+// to save a map by key. The saved setting never expires
 //
 // Parameters:
 // - ctx: the context
@@ -634,13 +1033,28 @@ func (st *store) SetAny(ctx context.Context, key string, value interface{}, seco
 // Returns:
 // - error - nil if no error, error otherwise
 func (st *store) SetMap(ctx context.Context, key string, value map[string]any) error {
+	return st.SetMapWithTTL(ctx, key, value, 0)
+}
+
+// SetMapWithTTL is a shortcut method to save a map by key, which expires
+// after the specified number of seconds, use GetMap to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+// - seconds: the seconds until the setting expires, 0 or less never expires
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetMapWithTTL(ctx context.Context, key string, value map[string]any, seconds int64) error {
 	jsonValue, jsonError := json.Marshal(value)
 
 	if jsonError != nil {
 		return jsonError
 	}
 
-	return st.Set(ctx, key, string(jsonValue))
+	return st.SetWithTTL(ctx, key, string(jsonValue), seconds)
 }
 
 // settingSelectQuery builds the select query
@@ -661,7 +1075,10 @@ func (store *store) settingSelectQuery(options SettingQueryInterface) (selectDat
 		return nil, []any{}, err
 	}
 
-	q := goqu.Dialect(store.dbDriverName).From(store.settingTableName)
+	q := goqu.Dialect(store.dbDriverName).
+		From(store.settingTableName).
+		Where(store.tenantQueryExpression(options)).
+		Where(store.namespaceExpression())
 
 	if options.HasID() {
 		q = q.Where(goqu.C(COLUMN_ID).Eq(options.ID()))
@@ -672,7 +1089,33 @@ func (store *store) settingSelectQuery(options SettingQueryInterface) (selectDat
 	}
 
 	if options.HasKey() {
-		q = q.Where(goqu.C(COLUMN_SETTING_KEY).Eq(options.Key()))
+		q = q.Where(goqu.C(COLUMN_SETTING_KEY).Eq(store.namespacedKey(options.Key())))
+	}
+
+	if options.HasKeyIn() {
+		q = q.Where(goqu.C(COLUMN_SETTING_KEY).In(lo.Map(options.KeyIn(), func(key string, _ int) string {
+			return store.namespacedKey(key)
+		})))
+	}
+
+	if options.HasKeyLike() {
+		q = q.Where(keyLikeExpression(likeEscape(store.keyPrefix) + likePatternFromGlob(options.KeyLike())))
+	}
+
+	if options.HasKeyPrefix() {
+		q = q.Where(keyPrefixExpression(store.namespacedKey(options.KeyPrefix())))
+	}
+
+	if options.HasCreatedAtGte() {
+		q = q.Where(goqu.C(COLUMN_CREATED_AT).Gte(options.CreatedAtGte()))
+	}
+
+	if options.HasCreatedAtLte() {
+		q = q.Where(goqu.C(COLUMN_CREATED_AT).Lte(options.CreatedAtLte()))
+	}
+
+	if options.HasUpdatedAtGte() {
+		q = q.Where(goqu.C(COLUMN_UPDATED_AT).Gte(options.UpdatedAtGte()))
 	}
 
 	if !options.IsCountOnly() {
@@ -704,6 +1147,16 @@ func (store *store) settingSelectQuery(options SettingQueryInterface) (selectDat
 		columns = append(columns, column)
 	}
 
+	if !options.ExpiredIncluded() {
+		// rows created before the expiry column was added have no expiry
+		notExpired := goqu.Or(
+			goqu.C(COLUMN_EXPIRES_AT).IsNull(),
+			goqu.C(COLUMN_EXPIRES_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString()),
+		)
+
+		q = q.Where(notExpired)
+	}
+
 	if options.SoftDeletedIncluded() {
 		return q, columns, nil // soft deleted settings requested specifically
 	}
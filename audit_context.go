@@ -0,0 +1,55 @@
+package settingstore
+
+import "context"
+
+// auditContextKey is the key of the audit metadata in the context
+type auditContextKey struct{}
+
+// AuditMetadata describes who made a change and why, recorded
+// in the audit trail with every change made with the context
+type AuditMetadata struct {
+	// Actor is who made the change, i.e. a user ID or a service name
+	Actor string
+
+	// Reason is why the change was made, i.e. a ticket or a comment
+	Reason string
+
+	// RequestID correlates the change with the request which made it
+	RequestID string
+}
+
+// WithActor returns a copy of the context, carrying the actor
+// recorded in the audit trail
+func WithActor(ctx context.Context, actor string) context.Context {
+	metadata := AuditMetadataFromContext(ctx)
+	metadata.Actor = actor
+	return context.WithValue(ctx, auditContextKey{}, metadata)
+}
+
+// WithReason returns a copy of the context, carrying the reason
+// recorded in the audit trail
+func WithReason(ctx context.Context, reason string) context.Context {
+	metadata := AuditMetadataFromContext(ctx)
+	metadata.Reason = reason
+	return context.WithValue(ctx, auditContextKey{}, metadata)
+}
+
+// WithRequestID returns a copy of the context, carrying the request ID
+// recorded in the audit trail
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	metadata := AuditMetadataFromContext(ctx)
+	metadata.RequestID = requestID
+	return context.WithValue(ctx, auditContextKey{}, metadata)
+}
+
+// AuditMetadataFromContext returns the audit metadata carried by the context,
+// empty if there is none
+func AuditMetadataFromContext(ctx context.Context) AuditMetadata {
+	if ctx == nil {
+		return AuditMetadata{}
+	}
+
+	metadata, _ := ctx.Value(auditContextKey{}).(AuditMetadata)
+
+	return metadata
+}
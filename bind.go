@@ -0,0 +1,380 @@
+package settingstore
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// BindError is returned by Bind, listing every missing and invalid setting
+type BindError struct {
+	// Missing are the keys of the required settings, which are not set
+	// and have no default value
+	Missing []string
+
+	// Invalid are the settings which could not be converted to the field type
+	Invalid []*ParseError
+}
+
+func (e *BindError) Error() string {
+	messages := []string{}
+
+	if len(e.Missing) > 0 {
+		messages = append(messages, "missing required settings: "+strings.Join(e.Missing, ", "))
+	}
+
+	for _, invalid := range e.Invalid {
+		messages = append(messages, invalid.Error())
+	}
+
+	return "settingstore: bind failed: " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns the parse errors, so that errors.As finds them
+func (e *BindError) Unwrap() []error {
+	return lo.Map(e.Invalid, func(invalid *ParseError, _ int) error {
+		return invalid
+	})
+}
+
+// boundField is a struct field tagged with the key of a setting
+type boundField struct {
+	key          string
+	defaultValue string
+	hasDefault   bool
+	required     bool
+	value        reflect.Value
+}
+
+var (
+	typeDuration        = reflect.TypeOf(time.Duration(0))
+	typeTextMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Bind fills the fields of the struct pointed to by target from the store
+//
+// The fields are mapped with struct tags:
+//
+//	type Config struct {
+//		Port    int           `setting:"server.port" default:"8080"`
+//		Host    string        `setting:"server.host" required:"true"`
+//		Timeout time.Duration `setting:"server.timeout" default:"30s"`
+//	}
+//
+// All the referenced keys are loaded in one query. Nested structs without
+// a setting tag are walked too. Fields of settings which are not set take
+// their default value, or are left as they are if they have none.
+//
+// Parameters:
+// - ctx: the context
+// - store: the store to load the settings from
+// - target: a pointer to the struct to fill
+//
+// Returns:
+// - error - a *BindError listing every missing and invalid setting, nil if no error
+func Bind(ctx context.Context, store StoreInterface, target any) error {
+	if store == nil {
+		return errors.New("settingstore > bind. store cannot be nil")
+	}
+
+	if reflect.ValueOf(target).Kind() != reflect.Pointer {
+		return errors.New("settingstore > bind. target must be a pointer to a struct")
+	}
+
+	fields, err := bindFields(target)
+
+	if err != nil {
+		return err
+	}
+
+	values, err := bindLoadValues(ctx, store, fields)
+
+	if err != nil {
+		return err
+	}
+
+	return bindApply(store, fields, values)
+}
+
+// Save persists the fields of the struct tagged with setting keys to the store
+//
+// It is the reverse of Bind. The values are saved in the same format Bind
+// reads them (JSON for slices, maps and structs, text otherwise). All the
+// fields are encoded first, and then saved in a single transaction, so
+// that the struct is never saved partially
+//
+// Parameters:
+// - ctx: the context
+// - store: the store to save the settings to
+// - source: the struct, or a pointer to the struct, to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func Save(ctx context.Context, store StoreInterface, source any) error {
+	if store == nil {
+		return errors.New("settingstore > save. store cannot be nil")
+	}
+
+	fields, err := bindFields(source)
+
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{}
+
+	for _, field := range fields {
+		value, err := bindEncodeValue(field.value)
+
+		if err != nil {
+			return fmt.Errorf("settingstore > save. setting %q: %w", field.key, err)
+		}
+
+		values[field.key] = value
+	}
+
+	return store.SetMany(ctx, values)
+}
+
+// bindFields returns the fields of the struct tagged with setting keys
+func bindFields(target any) ([]boundField, error) {
+	value := reflect.ValueOf(target)
+
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, errors.New("settingstore > bind. target cannot be nil")
+		}
+
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, errors.New("settingstore > bind. target must be a struct or a pointer to a struct")
+	}
+
+	fields := []boundField{}
+
+	bindWalkStruct(value, &fields)
+
+	return fields, nil
+}
+
+// bindWalkStruct appends the tagged fields of the struct, walking nested structs
+func bindWalkStruct(structValue reflect.Value, fields *[]boundField) {
+	structType := structValue.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		fieldType := structType.Field(i)
+		fieldValue := structValue.Field(i)
+
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		key, tagged := fieldType.Tag.Lookup("setting")
+
+		if !tagged {
+			if isNestedStruct(fieldType.Type) {
+				bindWalkStruct(fieldValue, fields)
+			}
+
+			continue
+		}
+
+		if key == "" || key == "-" {
+			continue
+		}
+
+		defaultValue, hasDefault := fieldType.Tag.Lookup("default")
+		required, _ := strconv.ParseBool(fieldType.Tag.Get("required"))
+
+		*fields = append(*fields, boundField{
+			key:          key,
+			defaultValue: defaultValue,
+			hasDefault:   hasDefault,
+			required:     required,
+			value:        fieldValue,
+		})
+	}
+}
+
+// isNestedStruct returns true if the type is a struct which should be
+// walked for tagged fields, rather than decoded as a single value
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	if t.Implements(typeTextUnmarshaler) || reflect.PointerTo(t).Implements(typeTextUnmarshaler) {
+		return false // i.e. time.Time
+	}
+
+	return true
+}
+
+// bindLoadValues loads the values of the fields keys in one query. The
+// sensitive settings are bound unmasked, and recorded in the audit trail
+// as revealed
+func bindLoadValues(ctx context.Context, store StoreInterface, fields []boundField) (map[string]string, error) {
+	values := map[string]string{}
+
+	if len(fields) < 1 {
+		return values, nil
+	}
+
+	keys := lo.Uniq(lo.Map(fields, func(field boundField, _ int) string {
+		return field.key
+	}))
+
+	settings, err := revealSettings(ctx, store, SettingQuery().SetKeyIn(keys))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, setting := range settings {
+		values[setting.GetKey()] = setting.GetValue()
+	}
+
+	return values, nil
+}
+
+// bindApply sets the fields from the values, collecting all the errors
+func bindApply(store StoreInterface, fields []boundField, values map[string]string) error {
+	bindError := &BindError{}
+
+	for _, field := range fields {
+		value, found := values[field.key]
+
+		if !found && field.hasDefault {
+			value, found = field.defaultValue, true
+		}
+
+		if !found {
+			if field.required {
+				bindError.Missing = append(bindError.Missing, field.key)
+			}
+
+			continue
+		}
+
+		if err := bindDecodeValue(value, field.value); err != nil {
+			sensitive := isSensitiveSetting(store, field.key)
+			bindError.Invalid = append(bindError.Invalid, newParseError(field.key, field.value.Type().String(), value, err, sensitive))
+		}
+	}
+
+	if len(bindError.Missing) > 0 || len(bindError.Invalid) > 0 {
+		return bindError
+	}
+
+	return nil
+}
+
+// bindDecodeValue converts the value of a setting to the type of the field
+func bindDecodeValue(value string, field reflect.Value) error {
+	if field.CanAddr() && field.Addr().Type().Implements(typeTextUnmarshaler) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	if field.Type() == typeDuration {
+		duration, err := time.ParseDuration(value)
+
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(int64(duration))
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(parsed)
+	default:
+		decoded := reflect.New(field.Type())
+
+		if err := decodeValue(value, decoded.Interface()); err != nil {
+			return err
+		}
+
+		field.Set(decoded.Elem())
+	}
+
+	return nil
+}
+
+// bindEncodeValue converts the field to the value of a setting
+func bindEncodeValue(field reflect.Value) (string, error) {
+	if field.Type().Implements(typeTextMarshaler) {
+		text, err := field.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	if field.Type() == typeDuration {
+		return time.Duration(field.Int()).String(), nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, field.Type().Bits()), nil
+	}
+
+	jsonValue, err := json.Marshal(field.Interface())
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonValue), nil
+}
@@ -0,0 +1,190 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type bindTestConfig struct {
+	Server struct {
+		Host    string        `setting:"server.host" required:"true"`
+		Port    int           `setting:"server.port" default:"8080"`
+		Timeout time.Duration `setting:"server.timeout" default:"30s"`
+	}
+	Debug     bool      `setting:"app.debug"`
+	Ratio     float64   `setting:"app.ratio"`
+	Origins   []string  `setting:"app.origins"`
+	StartedAt time.Time `setting:"app.started_at"`
+	Ignored   string
+}
+
+func TestBind(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	values := map[string]string{
+		"server.host":    "localhost",
+		"app.debug":      "true",
+		"app.ratio":      "0.5",
+		"app.origins":    `["a.com","b.com"]`,
+		"app.started_at": "2024-05-01T10:30:00Z",
+	}
+
+	for key, value := range values {
+		if err := store.Set(ctx, key, value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	config := bindTestConfig{}
+
+	if err := Bind(ctx, store, &config); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if config.Server.Host != "localhost" || config.Server.Port != 8080 || config.Server.Timeout != 30*time.Second {
+		t.Fatalf("unexpected server config: %+v", config.Server)
+	}
+
+	if !config.Debug || config.Ratio != 0.5 {
+		t.Fatalf("unexpected app config: %+v", config)
+	}
+
+	if !reflect.DeepEqual(config.Origins, []string{"a.com", "b.com"}) {
+		t.Fatal("unexpected origins:", config.Origins)
+	}
+
+	if !config.StartedAt.Equal(time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Fatal("unexpected started at:", config.StartedAt)
+	}
+}
+
+func TestBind_AggregatesErrors(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "server.port", "eighty"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "app.debug", "maybe"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	config := bindTestConfig{}
+
+	err = Bind(ctx, store, &config)
+
+	var bindError *BindError
+
+	if !errors.As(err, &bindError) {
+		t.Fatal("error MUST be a *BindError, found: ", err)
+	}
+
+	if !reflect.DeepEqual(bindError.Missing, []string{"server.host"}) {
+		t.Fatal("unexpected missing keys:", bindError.Missing)
+	}
+
+	if len(bindError.Invalid) != 2 {
+		t.Fatal("unexpected invalid settings:", bindError.Invalid)
+	}
+
+	var parseError *ParseError
+
+	if !errors.As(err, &parseError) {
+		t.Fatal("errors.As MUST find the parse errors")
+	}
+}
+
+func TestSave(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	config := bindTestConfig{Debug: true, Origins: []string{"a.com"}}
+	config.Server.Host = "example.com"
+	config.Server.Port = 443
+	config.Server.Timeout = time.Minute
+
+	if err := Save(ctx, store, config); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value, _ := store.Get(ctx, "server.timeout", ""); value != "1m0s" {
+		t.Fatal("unexpected saved timeout:", value)
+	}
+
+	loaded := bindTestConfig{}
+
+	if err := Bind(ctx, store, &loaded); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !reflect.DeepEqual(loaded, config) {
+		t.Fatalf("loaded config MUST match the saved one: %+v != %+v", loaded, config)
+	}
+}
+
+func TestSave_NothingSavedOnError(t *testing.T) {
+	db, store := initSharedMemoryStore(t)
+
+	ctx := context.Background()
+
+	config := struct {
+		Name     string `setting:"app.name"`
+		Callback func() `setting:"app.callback"`
+		Port     int    `setting:"app.port"`
+	}{Name: "Acme", Callback: func() {}, Port: 443}
+
+	if err := Save(ctx, store, config); err == nil {
+		t.Fatal("Field which cannot be encoded MUST fail the save")
+	}
+
+	count, err := store.SettingCount(ctx, SettingQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("Struct MUST NOT be saved partially, found settings:", count)
+	}
+
+	// the database rejects the port, in the middle of the save
+	_, err = db.Exec("CREATE TRIGGER reject_port BEFORE INSERT ON setting WHEN NEW.setting_key = 'app.port' " +
+		"BEGIN SELECT RAISE(ABORT, 'port rejected'); END")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	saved := struct {
+		Name string `setting:"app.name"`
+		Port int    `setting:"app.port"`
+	}{Name: "Acme", Port: 443}
+
+	if err := Save(ctx, store, saved); err == nil {
+		t.Fatal("Field which cannot be saved MUST fail the save")
+	}
+
+	if value, _ := store.Get(ctx, "app.name", ""); value != "" {
+		t.Fatal("Struct MUST NOT be saved partially, found:", value)
+	}
+}
@@ -0,0 +1,172 @@
+package settingstore
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheStats contains the counters of the setting cache, for monitoring
+type CacheStats struct {
+	// Hits is the number of lookups served from the cache
+	Hits int64
+
+	// Misses is the number of lookups which went to the database
+	Misses int64
+
+	// Entries is the number of entries currently in the cache
+	Entries int
+}
+
+// settingCache is an in-process LRU cache of setting values by key
+//
+// Missing keys may be cached too (negative caching), so that repeated
+// lookups of keys which are not set do not go to the database.
+type settingCache struct {
+	mutex       sync.Mutex
+	maxEntries  int
+	ttl         time.Duration
+	missingKeys bool
+	entries     map[string]*list.Element
+	lru         *list.List
+	generation  uint64
+	hits        int64
+	misses      int64
+}
+
+// settingCacheEntry is a cached setting value
+type settingCacheEntry struct {
+	key       string
+	settingID string
+	value     string
+	found     bool
+	expiresAt time.Time
+}
+
+// newSettingCache creates a new setting cache
+func newSettingCache(maxEntries int, ttl time.Duration, missingKeys bool) *settingCache {
+	return &settingCache{
+		maxEntries:  maxEntries,
+		ttl:         ttl,
+		missingKeys: missingKeys,
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+	}
+}
+
+// get returns the cached entry for the key, counting the hit or miss
+//
+// Returns:
+// - entry - the cached entry, if found
+// - hit - true if a fresh entry was found, false otherwise
+// - generation - pass to set, to detect invalidations during the lookup
+func (cache *settingCache) get(key string) (entry settingCacheEntry, hit bool, generation uint64) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, exists := cache.entries[key]
+
+	if exists {
+		cached := element.Value.(*settingCacheEntry)
+
+		if time.Now().Before(cached.expiresAt) {
+			cache.lru.MoveToFront(element)
+			cache.hits++
+			return *cached, true, cache.generation
+		}
+
+		cache.removeElement(element)
+	}
+
+	cache.misses++
+
+	return settingCacheEntry{}, false, cache.generation
+}
+
+// set caches the entry, unless the cache was invalidated since generation
+// was obtained (the entry may be stale), or it is a miss and missing
+// keys are not cached
+func (cache *settingCache) set(entry settingCacheEntry, generation uint64) {
+	if !entry.found && !cache.missingKeys {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if generation != cache.generation {
+		return
+	}
+
+	ttlExpiresAt := time.Now().Add(cache.ttl)
+
+	if entry.expiresAt.IsZero() || entry.expiresAt.After(ttlExpiresAt) {
+		entry.expiresAt = ttlExpiresAt
+	}
+
+	if element, exists := cache.entries[entry.key]; exists {
+		element.Value = &entry
+		cache.lru.MoveToFront(element)
+		return
+	}
+
+	cache.entries[entry.key] = cache.lru.PushFront(&entry)
+
+	for cache.maxEntries > 0 && cache.lru.Len() > cache.maxEntries {
+		cache.removeElement(cache.lru.Back())
+	}
+}
+
+// invalidateKey removes the entry for the key
+func (cache *settingCache) invalidateKey(key string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generation++
+
+	if element, exists := cache.entries[key]; exists {
+		cache.removeElement(element)
+	}
+}
+
+// invalidateID removes the entries for the setting with the ID
+func (cache *settingCache) invalidateID(settingID string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generation++
+
+	for _, element := range cache.entries {
+		if element.Value.(*settingCacheEntry).settingID == settingID {
+			cache.removeElement(element)
+		}
+	}
+}
+
+// invalidateAll removes all the entries
+func (cache *settingCache) invalidateAll() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generation++
+	cache.entries = map[string]*list.Element{}
+	cache.lru.Init()
+}
+
+// stats returns the counters of the cache
+func (cache *settingCache) stats() CacheStats {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return CacheStats{
+		Hits:    cache.hits,
+		Misses:  cache.misses,
+		Entries: cache.lru.Len(),
+	}
+}
+
+// removeElement removes the element, the mutex must be held
+func (cache *settingCache) removeElement(element *list.Element) {
+	cache.lru.Remove(element)
+	delete(cache.entries, element.Value.(*settingCacheEntry).key)
+}
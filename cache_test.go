@@ -0,0 +1,63 @@
+package settingstore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettingCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newSettingCache(2, time.Minute, false)
+
+	_, _, generation := cache.get("a")
+	cache.set(settingCacheEntry{key: "a", value: "1", found: true}, generation)
+	cache.set(settingCacheEntry{key: "b", value: "2", found: true}, generation)
+
+	if _, hit, _ := cache.get("a"); !hit {
+		t.Fatal("a MUST be cached")
+	}
+
+	cache.set(settingCacheEntry{key: "c", value: "3", found: true}, generation)
+
+	if _, hit, _ := cache.get("b"); hit {
+		t.Fatal("b MUST be evicted as the least recently used")
+	}
+
+	if _, hit, _ := cache.get("a"); !hit {
+		t.Fatal("a MUST still be cached")
+	}
+
+	if stats := cache.stats(); stats.Entries != 2 {
+		t.Fatal("unexpected number of entries:", stats.Entries)
+	}
+}
+
+func TestSettingCache_SkipsStaleFill(t *testing.T) {
+	cache := newSettingCache(10, time.Minute, true)
+
+	_, _, generation := cache.get("a")
+
+	cache.invalidateKey("a") // a write happened during the database lookup
+
+	cache.set(settingCacheEntry{key: "a", value: "stale", found: true}, generation)
+
+	if _, hit, _ := cache.get("a"); hit {
+		t.Fatal("a stale value MUST NOT be cached")
+	}
+}
+
+func TestSettingCache_RespectsSettingExpiry(t *testing.T) {
+	cache := newSettingCache(10, time.Minute, false)
+
+	_, _, generation := cache.get("a")
+
+	cache.set(settingCacheEntry{
+		key:       "a",
+		value:     "1",
+		found:     true,
+		expiresAt: time.Now().Add(-time.Second),
+	}, generation)
+
+	if _, hit, _ := cache.get("a"); hit {
+		t.Fatal("an expired setting MUST NOT be served from the cache")
+	}
+}
@@ -6,5 +6,25 @@ const (
 	COLUMN_SETTING_VALUE   = "setting_value"
 	COLUMN_CREATED_AT      = "created_at"
 	COLUMN_UPDATED_AT      = "updated_at"
+	COLUMN_EXPIRES_AT      = "expires_at"
 	COLUMN_SOFT_DELETED_AT = "soft_deleted_at"
+	COLUMN_TENANT_ID       = "tenant_id"
+	COLUMN_REVISION        = "revision"
+	COLUMN_SETTING_ID      = "setting_id"
+	COLUMN_ACTION          = "action"
+	COLUMN_OLD_VALUE       = "old_value"
+	COLUMN_NEW_VALUE       = "new_value"
+	COLUMN_VERSION         = "version"
+	COLUMN_ACTOR           = "actor"
+	COLUMN_REASON          = "reason"
+	COLUMN_REQUEST_ID      = "request_id"
+	COLUMN_SEQUENCE        = "sequence"
+	COLUMN_PREVIOUS_HASH   = "previous_hash"
+	COLUMN_HASH            = "hash"
+	COLUMN_LEASE_NAME      = "lease_name"
+	COLUMN_OWNER           = "owner"
 )
+
+// REDACTED_VALUE replaces the values of the sensitive settings,
+// wherever they are listed or logged
+const REDACTED_VALUE = "[REDACTED]"
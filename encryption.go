@@ -0,0 +1,152 @@
+package settingstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// encryptedValuePrefix starts the values sealed by the keyring, which are
+// written as "enc:v1:<key ID>:<base64 of the nonce and the ciphertext>"
+const encryptedValuePrefix = "enc:v1:"
+
+// encryptionKeyring seals and opens the values of the secret settings
+// with AES-256-GCM
+//
+// The ciphertext is bound to the tenant and the full key of the setting,
+// as additional authenticated data, so that it cannot be copied over
+// the value of another setting.
+type encryptionKeyring struct {
+	mutex       sync.RWMutex
+	ciphers     map[string]cipher.AEAD
+	activeKeyID string
+	secrets     keyMatcher
+}
+
+// newEncryptionKeyring creates a new keyring, sealing with the active key
+func newEncryptionKeyring(keys map[string][]byte, activeKeyID string, secretKeys []string, secretKeyPrefixes []string) (*encryptionKeyring, error) {
+	keyring := &encryptionKeyring{
+		ciphers: map[string]cipher.AEAD{},
+		secrets: newKeyMatcher(secretKeys, secretKeyPrefixes),
+	}
+
+	for keyID, key := range keys {
+		if keyID == "" || strings.Contains(keyID, ":") {
+			return nil, fmt.Errorf("setting store: encryption key ID %q must not be empty nor contain a colon", keyID)
+		}
+
+		if len(key) != 32 {
+			return nil, fmt.Errorf("setting store: encryption key %q must be 32 bytes long for AES-256", keyID)
+		}
+
+		block, err := aes.NewCipher(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keyring.ciphers[keyID] = aead
+	}
+
+	if err := keyring.setActiveKeyID(activeKeyID); err != nil {
+		return nil, err
+	}
+
+	return keyring, nil
+}
+
+// setActiveKeyID sets the key sealing the new values
+func (keyring *encryptionKeyring) setActiveKeyID(keyID string) error {
+	if _, exists := keyring.ciphers[keyID]; !exists {
+		return fmt.Errorf("setting store: encryption key %q is not in the keyring", keyID)
+	}
+
+	keyring.mutex.Lock()
+	defer keyring.mutex.Unlock()
+
+	keyring.activeKeyID = keyID
+
+	return nil
+}
+
+// activeKey returns the ID of the key sealing the new values
+func (keyring *encryptionKeyring) activeKey() string {
+	keyring.mutex.RLock()
+	defer keyring.mutex.RUnlock()
+
+	return keyring.activeKeyID
+}
+
+// seal encrypts the value with the active key
+func (keyring *encryptionKeyring) seal(tenantID string, fullKey string, value string) (string, error) {
+	keyID := keyring.activeKey()
+	aead := keyring.ciphers[keyID]
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), encryptionAdditionalData(tenantID, fullKey))
+
+	return encryptedValuePrefix + keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts the sealed value with the key it was sealed with.
+// A value which is not sealed is returned as it is, i.e. one saved
+// before its key became secret
+func (keyring *encryptionKeyring) open(tenantID string, fullKey string, value string) (string, error) {
+	keyID, encoded, sealed := keyring.parse(value)
+
+	if !sealed {
+		return value, nil
+	}
+
+	aead, exists := keyring.ciphers[keyID]
+
+	if !exists {
+		return "", fmt.Errorf("%w: key %q sealing setting %q is not in the keyring", ErrDecryption, keyID, fullKey)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("%w: setting %q is malformed", ErrDecryption, fullKey)
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], encryptionAdditionalData(tenantID, fullKey))
+
+	if err != nil {
+		return "", fmt.Errorf("%w: setting %q: %v", ErrDecryption, fullKey, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// parse returns the key ID and the encoded ciphertext of a sealed value
+func (keyring *encryptionKeyring) parse(value string) (keyID string, encoded string, sealed bool) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return "", "", false
+	}
+
+	keyID, encoded, found := strings.Cut(strings.TrimPrefix(value, encryptedValuePrefix), ":")
+
+	return keyID, encoded, found
+}
+
+// encryptionAdditionalData returns the additional authenticated data,
+// binding the ciphertext to the tenant and the full key of the setting
+func encryptionAdditionalData(tenantID string, fullKey string) []byte {
+	return []byte(tenantID + "\x00" + fullKey)
+}
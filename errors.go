@@ -0,0 +1,63 @@
+package settingstore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrConflict is returned by SettingUpdate, when the setting was changed
+// or deleted by someone else since it was loaded
+var ErrConflict = errors.New("settingstore: the setting was changed since it was loaded")
+
+// ErrDecryption is returned when the value of a secret setting cannot be
+// decrypted, because its key is not in the keyring or it was tampered with
+var ErrDecryption = errors.New("settingstore: the secret setting cannot be decrypted")
+
+// ParseError is returned by the typed getters, when the value
+// of a setting cannot be parsed as the expected type
+type ParseError struct {
+	// Key is the key of the setting
+	Key string
+
+	// Type is the expected type, i.e. "int", "bool", "duration"
+	Type string
+
+	// Value is the value which could not be parsed, masked
+	// if the setting is sensitive
+	Value string
+
+	// Err is the underlying parse error
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("settingstore: setting %q is not a valid %s: %v", e.Key, e.Type, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// errInvalidValue replaces the underlying parse error of a sensitive
+// setting, as the error may contain the value
+var errInvalidValue = errors.New("invalid value")
+
+// newParseError returns the parse error of the value of the setting.
+// If the setting is sensitive, the value is masked, and the underlying
+// error is replaced with one which does not contain the value
+func newParseError(key string, typeName string, value string, err error, sensitive bool) *ParseError {
+	if !sensitive {
+		return &ParseError{Key: key, Type: typeName, Value: value, Err: err}
+	}
+
+	var numError *strconv.NumError
+
+	if errors.As(err, &numError) {
+		err = numError.Err // i.e. strconv.ErrSyntax, without the value
+	} else if !errors.Is(err, strconv.ErrSyntax) && !errors.Is(err, strconv.ErrRange) {
+		err = errInvalidValue
+	}
+
+	return &ParseError{Key: key, Type: typeName, Value: REDACTED_VALUE, Err: err}
+}
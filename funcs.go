@@ -1,6 +1,12 @@
 package settingstore
 
-import "os"
+import (
+	"os"
+	"strings"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/sb"
+)
 
 // fileExists checks if a file exists
 func fileExists(filePath string) bool {
@@ -8,3 +14,91 @@ func fileExists(filePath string) bool {
 
 	return !os.IsNotExist(err)
 }
+
+// expiresAtFromSeconds returns the expiry datetime for a setting
+// which expires after the specified seconds. If seconds is 0 or less,
+// the setting never expires
+func expiresAtFromSeconds(seconds int64) string {
+	if seconds <= 0 {
+		return sb.MAX_DATETIME
+	}
+
+	return carbon.Now(carbon.UTC).AddSeconds(int(seconds)).ToDateTimeString(carbon.UTC)
+}
+
+// isPastDateTime returns true if the datetime is set, and is not in the future
+func isPastDateTime(dateTime string) bool {
+	if dateTime == "" {
+		return false
+	}
+
+	return carbon.Parse(dateTime, carbon.UTC).Lte(carbon.Now(carbon.UTC))
+}
+
+// likeEscaper escapes the LIKE wildcards with the "!" escape character,
+// which has no special meaning in the string literals of the supported
+// databases (unlike the backslash in MySQL)
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// likeEscape escapes the LIKE wildcards in the value, so that it is matched literally
+func likeEscape(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// likePatternFromGlob converts a pattern with * and ? wildcards to a LIKE
+// pattern, escaping the characters which are wildcards for LIKE only
+func likePatternFromGlob(glob string) string {
+	var pattern strings.Builder
+
+	for _, character := range glob {
+		switch character {
+		case '*':
+			pattern.WriteString("%")
+		case '?':
+			pattern.WriteString("_")
+		default:
+			pattern.WriteString(likeEscape(string(character)))
+		}
+	}
+
+	return pattern.String()
+}
+
+// keyMatcher matches the full keys of the settings, with their namespace,
+// against a set of keys and of key prefixes
+type keyMatcher struct {
+	keys     map[string]bool
+	prefixes []string
+}
+
+// newKeyMatcher creates a new matcher of the keys and the key prefixes
+func newKeyMatcher(keys []string, prefixes []string) keyMatcher {
+	matcher := keyMatcher{keys: map[string]bool{}, prefixes: prefixes}
+
+	for _, key := range keys {
+		matcher.keys[key] = true
+	}
+
+	return matcher
+}
+
+// isEmpty returns true if the matcher matches no key
+func (matcher keyMatcher) isEmpty() bool {
+	return len(matcher.keys) == 0 && len(matcher.prefixes) == 0
+}
+
+// matches returns true if the full key is one of the keys,
+// or starts with one of the key prefixes
+func (matcher keyMatcher) matches(fullKey string) bool {
+	if matcher.keys[fullKey] {
+		return true
+	}
+
+	for _, prefix := range matcher.prefixes {
+		if strings.HasPrefix(fullKey, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
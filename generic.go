@@ -0,0 +1,199 @@
+package settingstore
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// valueFinder is implemented by the stores, which can find
+// a value by key through their cache
+type valueFinder interface {
+	findValueByKey(ctx context.Context, settingKey string) (string, bool, error)
+}
+
+// sensitivityChecker is implemented by the stores, which know
+// if the value of a setting is sensitive
+type sensitivityChecker interface {
+	isSensitiveSettingKey(settingKey string) bool
+}
+
+// settingLister is implemented by the stores, which can list the
+// settings with the values of the sensitive ones unmasked
+type settingLister interface {
+	settingList(ctx context.Context, query SettingQueryInterface) ([]SettingInterface, error)
+	revealList(ctx context.Context, query SettingQueryInterface) ([]SettingInterface, error)
+}
+
+// listSettings lists the settings matching the query, with their values
+// unmasked if the store can list them so. The values must not leave the
+// store, i.e. they are only compared
+func listSettings(ctx context.Context, store StoreInterface, query SettingQueryInterface) ([]SettingInterface, error) {
+	if lister, ok := store.(settingLister); ok {
+		return lister.settingList(ctx, query)
+	}
+
+	return store.SettingList(ctx, query)
+}
+
+// revealSettings lists the settings matching the query, with their values
+// unmasked if the store can list them so, recording in the audit trail
+// that the sensitive ones were revealed
+func revealSettings(ctx context.Context, store StoreInterface, query SettingQueryInterface) ([]SettingInterface, error) {
+	if lister, ok := store.(settingLister); ok {
+		return lister.revealList(ctx, query)
+	}
+
+	return store.SettingList(ctx, query)
+}
+
+// GetAs gets the value of a setting by key decoded into the type T,
+// or a default if not found
+//
+// The value is decoded as follows:
+// - if *T implements encoding.TextUnmarshaler, the raw value is passed to UnmarshalText
+// - if T is a string, the raw value is returned as is
+// - otherwise the value is decoded as JSON into T, using json.Number for
+// numbers decoded into interfaces, so that large integers keep their precision
+//
+// Parameters:
+// - ctx: the context
+// - store: the store to get the setting from
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - T - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value cannot be decoded into T, nil if no error
+func GetAs[T any](ctx context.Context, store StoreInterface, settingKey string, valueDefault T) (T, error) {
+	if store == nil {
+		return valueDefault, errors.New("settingstore > get as. store cannot be nil")
+	}
+
+	value, found, err := findValue(ctx, store, settingKey)
+
+	if err != nil {
+		return valueDefault, err
+	}
+
+	if !found {
+		return valueDefault, nil
+	}
+
+	var decoded T
+
+	if err := decodeValue(value, &decoded); err != nil {
+		return valueDefault, newParseError(settingKey, reflect.TypeOf(&decoded).Elem().String(), value, err, isSensitiveSetting(store, settingKey))
+	}
+
+	return decoded, nil
+}
+
+// SetAs saves a value of the type T by key, use GetAs to extract
+//
+// The value is encoded as follows:
+// - if T implements encoding.TextMarshaler, the value of MarshalText is saved
+// - if T is a string, the value is saved as is
+// - otherwise the value is saved as JSON
+//
+// Parameters:
+// - ctx: the context
+// - store: the store to save the setting to
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func SetAs[T any](ctx context.Context, store StoreInterface, settingKey string, value T) error {
+	if store == nil {
+		return errors.New("settingstore > set as. store cannot be nil")
+	}
+
+	encoded, err := encodeValue(value)
+
+	if err != nil {
+		return err
+	}
+
+	return store.Set(ctx, settingKey, encoded)
+}
+
+// findValue finds the value of a setting by key, through the cache
+// of the store if it has one
+func findValue(ctx context.Context, store StoreInterface, settingKey string) (string, bool, error) {
+	if finder, ok := store.(valueFinder); ok {
+		return finder.findValueByKey(ctx, settingKey)
+	}
+
+	setting, err := store.SettingFindByKey(ctx, settingKey)
+
+	if err != nil || setting == nil {
+		return "", false, err
+	}
+
+	return setting.GetValue(), true, nil
+}
+
+// isSensitiveSetting returns true if the store knows the value
+// of the setting is sensitive
+func isSensitiveSetting(store StoreInterface, settingKey string) bool {
+	checker, ok := store.(sensitivityChecker)
+	return ok && checker.isSensitiveSettingKey(settingKey)
+}
+
+// decodeValue decodes the value of a setting into the target pointer
+func decodeValue(value string, target any) error {
+	if unmarshaler, ok := target.(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	if stringTarget, ok := target.(*string); ok {
+		*stringTarget = value
+		return nil
+	}
+
+	targetValue := reflect.ValueOf(target).Elem()
+
+	if targetValue.Kind() == reflect.String {
+		targetValue.SetString(value) // named string types
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+
+	return nil
+}
+
+// encodeValue encodes the value to be saved as a setting
+func encodeValue(value any) (string, error) {
+	if marshaler, ok := value.(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+
+	reflectValue := reflect.ValueOf(value)
+
+	if reflectValue.IsValid() && reflectValue.Kind() == reflect.String {
+		return reflectValue.String(), nil
+	}
+
+	jsonValue, err := json.Marshal(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonValue), nil
+}
@@ -0,0 +1,133 @@
+package settingstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+type smtpConfig struct {
+	Host    string        `json:"host"`
+	Port    int           `json:"port"`
+	Timeout time.Duration `json:"timeout"`
+	Extra   any           `json:"extra"`
+}
+
+func TestGetAsSetAs_Struct(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	config := smtpConfig{Host: "smtp.test.com", Port: 587, Timeout: time.Second}
+
+	if err := SetAs(ctx, store, "mail.smtp", config); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	found, err := GetAs(ctx, store, "mail.smtp", smtpConfig{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found.Host != config.Host || found.Port != config.Port || found.Timeout != config.Timeout {
+		t.Fatalf("unexpected value: %+v", found)
+	}
+
+	missing, err := GetAs(ctx, store, "mail.missing", smtpConfig{Port: 25})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if missing.Port != 25 {
+		t.Fatalf("GetAs MUST return the default value, found: %+v", missing)
+	}
+}
+
+func TestGetAs_LargeIntegersKeepPrecision(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "sequence", `{"extra": 9007199254740993}`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	found, err := GetAs(ctx, store, "sequence", smtpConfig{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	number, ok := found.Extra.(json.Number)
+
+	if !ok {
+		t.Fatalf("Extra MUST be a json.Number, found: %T", found.Extra)
+	}
+
+	if number.String() != "9007199254740993" {
+		t.Fatal("unexpected number:", number.String())
+	}
+}
+
+func TestGetAsSetAs_TextMarshaler(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := SetAs(ctx, store, "server.ip", netip.MustParseAddr("10.0.0.1")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	raw, err := store.Get(ctx, "server.ip", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if raw != "10.0.0.1" {
+		t.Fatal("the value MUST be saved as text, found: ", raw)
+	}
+
+	found, err := GetAs(ctx, store, "server.ip", netip.Addr{})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found.String() != "10.0.0.1" {
+		t.Fatal("unexpected value:", found.String())
+	}
+
+	if err := store.Set(ctx, "server.ip", "not an ip"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = GetAs(ctx, store, "server.ip", netip.Addr{})
+
+	var parseError *ParseError
+
+	if !errors.As(err, &parseError) {
+		t.Fatal("error MUST be a *ParseError, found: ", err)
+	}
+
+	if parseError.Type != "netip.Addr" {
+		t.Fatal("unexpected type:", parseError.Type)
+	}
+}
@@ -8,6 +8,7 @@ require (
 	github.com/gouniverse/uid v1.5.0
 	github.com/mattn/go-sqlite3 v1.14.24
 	github.com/samber/lo v1.49.1
+	golang.org/x/sync v0.12.0
 )
 
 require (
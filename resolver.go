@@ -0,0 +1,158 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// ResolverScope is one level of the settings hierarchy of a Resolver
+type ResolverScope struct {
+	// Name identifies the scope in the resolved settings, i.e. "user"
+	Name string
+
+	// Store holds the settings of the scope, usually a ForTenant
+	// or Namespace view of a shared store
+	Store StoreInterface
+}
+
+// ResolvedSetting is a setting value, and the scope it came from
+type ResolvedSetting struct {
+	Key   string
+	Value string
+	Scope string
+
+	// Sensitive is true if the value is masked, Resolve or Reveal
+	// on the store of the scope return it
+	Sensitive bool
+}
+
+// Resolver resolves settings through a hierarchy of scopes, where
+// the more specific scopes override the more general ones
+type Resolver struct {
+	scopes []ResolverScope
+}
+
+// NewResolver creates a new resolver
+//
+// The scopes are ordered from the most specific to the most general,
+// as they are looked up in this order:
+//
+//	resolver, err := settingstore.NewResolver(
+//		settingstore.ResolverScope{Name: "user", Store: store.ForTenant("user:" + userID)},
+//		settingstore.ResolverScope{Name: "team", Store: store.ForTenant("team:" + teamID)},
+//		settingstore.ResolverScope{Name: "organization", Store: store.ForTenant("org:" + orgID)},
+//		settingstore.ResolverScope{Name: "global", Store: store},
+//	)
+//
+// Parameters:
+// - scopes: the scopes, from the most specific to the most general
+//
+// Returns:
+// - *Resolver - the resolver
+// - error - nil if no error, error otherwise
+func NewResolver(scopes ...ResolverScope) (*Resolver, error) {
+	if len(scopes) < 1 {
+		return nil, errors.New("settingstore > new resolver. at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if scope.Name == "" {
+			return nil, errors.New("settingstore > new resolver. scope name cannot be empty")
+		}
+
+		if scope.Store == nil {
+			return nil, errors.New("settingstore > new resolver. store of scope " + scope.Name + " cannot be nil")
+		}
+	}
+
+	names := lo.Map(scopes, func(scope ResolverScope, _ int) string {
+		return scope.Name
+	})
+
+	if duplicates := lo.FindDuplicates(names); len(duplicates) > 0 {
+		return nil, errors.New("settingstore > new resolver. duplicate scope names: " + strings.Join(duplicates, ", "))
+	}
+
+	return &Resolver{scopes: scopes}, nil
+}
+
+// Resolve finds the value of a setting in the most specific scope which has it
+//
+// The scopes are looked up in order, and the lookup stops at the first
+// scope which has the setting, so the more general scopes are only
+// queried if needed
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to resolve
+//
+// Returns:
+// - *ResolvedSetting - the value and the scope it came from, nil if no scope has it
+// - error - nil if no error, error otherwise
+func (r *Resolver) Resolve(ctx context.Context, settingKey string) (*ResolvedSetting, error) {
+	if settingKey == "" {
+		return nil, errors.New("settingstore > resolve. key cannot be empty")
+	}
+
+	for _, scope := range r.scopes {
+		value, found, err := findValue(ctx, scope.Store, settingKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			return &ResolvedSetting{
+				Key:   settingKey,
+				Value: value,
+				Scope: scope.Name,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ResolveAll returns the effective settings under the key prefix, merged
+// from all the scopes. Each setting comes from the most specific scope
+// which has it. The values of the sensitive settings are masked
+//
+// Parameters:
+// - ctx: the context
+// - keyPrefix: the prefix of the keys to resolve, i.e. "ui.", empty for all the keys
+//
+// Returns:
+// - map[string]ResolvedSetting - the resolved settings, by key
+// - error - nil if no error, error otherwise
+func (r *Resolver) ResolveAll(ctx context.Context, keyPrefix string) (map[string]ResolvedSetting, error) {
+	resolved := map[string]ResolvedSetting{}
+
+	// from the most general to the most specific, so that the latter override
+	for _, scope := range lo.Reverse(append([]ResolverScope{}, r.scopes...)) {
+		query := SettingQuery()
+
+		if keyPrefix != "" {
+			query.SetKeyPrefix(keyPrefix)
+		}
+
+		settings, err := scope.Store.SettingList(ctx, query)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, setting := range settings {
+			resolved[setting.GetKey()] = ResolvedSetting{
+				Key:       setting.GetKey(),
+				Value:     setting.GetValue(),
+				Scope:     scope.Name,
+				Sensitive: setting.IsSensitive(),
+			}
+		}
+	}
+
+	return resolved, nil
+}
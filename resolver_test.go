@@ -0,0 +1,111 @@
+package settingstore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolver(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	user := store.ForTenant("user:1")
+	team := store.ForTenant("team:1")
+
+	resolver, err := NewResolver(
+		ResolverScope{Name: "user", Store: user},
+		ResolverScope{Name: "team", Store: team},
+		ResolverScope{Name: "global", Store: store},
+	)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	values := []struct {
+		store StoreInterface
+		key   string
+		value string
+	}{
+		{store, "ui.theme", "light"},
+		{store, "ui.language", "en"},
+		{store, "ui.timezone", "UTC"},
+		{team, "ui.language", "de"},
+		{team, "ui.timezone", "Europe/Berlin"},
+		{user, "ui.timezone", "Europe/Sofia"},
+		{user, "mail.digest", "weekly"},
+	}
+
+	for _, value := range values {
+		if err := value.store.Set(ctx, value.key, value.value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	resolved, err := resolver.Resolve(ctx, "ui.language")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if resolved == nil || resolved.Value != "de" || resolved.Scope != "team" {
+		t.Fatal("Resolve MUST return the value of the most specific scope, found:", resolved)
+	}
+
+	resolved, err = resolver.Resolve(ctx, "ui.missing")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if resolved != nil {
+		t.Fatal("Resolve MUST return nil, if no scope has the setting, found:", resolved)
+	}
+
+	all, err := resolver.ResolveAll(ctx, "ui.")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := map[string]ResolvedSetting{
+		"ui.theme":    {Key: "ui.theme", Value: "light", Scope: "global"},
+		"ui.language": {Key: "ui.language", Value: "de", Scope: "team"},
+		"ui.timezone": {Key: "ui.timezone", Value: "Europe/Sofia", Scope: "user"},
+	}
+
+	if len(all) != len(expected) {
+		t.Fatal("ResolveAll MUST return the settings under the prefix only, found:", all)
+	}
+
+	for key, setting := range expected {
+		if all[key] != setting {
+			t.Fatal("unexpected resolved setting:", all[key], "expected:", setting)
+		}
+	}
+}
+
+func TestNewResolverValidation(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	if _, err := NewResolver(); err == nil {
+		t.Fatal("A resolver without scopes MUST be rejected")
+	}
+
+	if _, err := NewResolver(ResolverScope{Name: "global"}); err == nil {
+		t.Fatal("A scope without a store MUST be rejected")
+	}
+
+	if _, err := NewResolver(ResolverScope{Name: "global", Store: store}, ResolverScope{Name: "global", Store: store}); err == nil {
+		t.Fatal("Duplicate scope names MUST be rejected")
+	}
+}
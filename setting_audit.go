@@ -0,0 +1,129 @@
+package settingstore
+
+import (
+	"strconv"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/dataobject"
+	"github.com/gouniverse/uid"
+)
+
+// AUDIT_ACTION_REVEAL is the action of the audit entries recording
+// that the value of a sensitive setting was revealed
+const AUDIT_ACTION_REVEAL = "reveal"
+
+// SettingAuditEntryInterface is a change of a setting recorded in the
+// audit trail, with who made it and why
+type SettingAuditEntryInterface interface {
+	Data() map[string]string
+
+	GetAction() string
+	GetActor() string
+	GetCreatedAt() string
+	GetCreatedAtCarbon() *carbon.Carbon
+	GetHash() string
+	GetID() string
+	GetKey() string
+	GetNewValue() string
+	GetOldValue() string
+	GetPreviousHash() string
+	GetReason() string
+	GetRequestID() string
+	GetSequence() int64
+	GetSettingID() string
+	GetTenantID() string
+}
+
+var _ SettingAuditEntryInterface = (*SettingAuditEntry)(nil)
+
+// SettingAuditEntry type
+type SettingAuditEntry struct {
+	dataobject.DataObject
+}
+
+// == CONSTRUCTORS ============================================================
+
+func newSettingAuditEntry() *SettingAuditEntry {
+	o := &SettingAuditEntry{}
+	o.Set(COLUMN_ID, uid.HumanUid())
+	o.Set(COLUMN_CREATED_AT, carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
+	return o
+}
+
+func NewSettingAuditEntryFromExistingData(data map[string]string) SettingAuditEntryInterface {
+	o := &SettingAuditEntry{}
+	o.Hydrate(data)
+	return o
+}
+
+// == GETTERS =================================================================
+
+// GetAction returns the change, one of the HISTORY_ACTION_* constants
+func (o *SettingAuditEntry) GetAction() string {
+	return o.Get(COLUMN_ACTION)
+}
+
+// GetActor returns who made the change, empty if not known
+func (o *SettingAuditEntry) GetActor() string {
+	return o.Get(COLUMN_ACTOR)
+}
+
+func (o *SettingAuditEntry) GetCreatedAt() string {
+	return o.Get(COLUMN_CREATED_AT)
+}
+
+func (o *SettingAuditEntry) GetCreatedAtCarbon() *carbon.Carbon {
+	return carbon.Parse(o.GetCreatedAt(), carbon.UTC)
+}
+
+// GetHash returns the hash of the entry, chaining it to the previous entry
+func (o *SettingAuditEntry) GetHash() string {
+	return o.Get(COLUMN_HASH)
+}
+
+func (o *SettingAuditEntry) GetID() string {
+	return o.Get(COLUMN_ID)
+}
+
+func (o *SettingAuditEntry) GetKey() string {
+	return o.Get(COLUMN_SETTING_KEY)
+}
+
+// GetNewValue returns the value after the change, empty for a deletion
+func (o *SettingAuditEntry) GetNewValue() string {
+	return o.Get(COLUMN_NEW_VALUE)
+}
+
+// GetOldValue returns the value before the change, empty for a creation
+func (o *SettingAuditEntry) GetOldValue() string {
+	return o.Get(COLUMN_OLD_VALUE)
+}
+
+// GetPreviousHash returns the hash of the previous entry, empty for the first entry
+func (o *SettingAuditEntry) GetPreviousHash() string {
+	return o.Get(COLUMN_PREVIOUS_HASH)
+}
+
+// GetReason returns why the change was made, empty if not known
+func (o *SettingAuditEntry) GetReason() string {
+	return o.Get(COLUMN_REASON)
+}
+
+// GetRequestID returns the ID of the request which made the change, empty if not known
+func (o *SettingAuditEntry) GetRequestID() string {
+	return o.Get(COLUMN_REQUEST_ID)
+}
+
+// GetSequence returns the position of the entry in the audit trail, starting from 1
+func (o *SettingAuditEntry) GetSequence() int64 {
+	sequence, _ := strconv.ParseInt(o.Get(COLUMN_SEQUENCE), 10, 64)
+	return sequence
+}
+
+func (o *SettingAuditEntry) GetSettingID() string {
+	return o.Get(COLUMN_SETTING_ID)
+}
+
+func (o *SettingAuditEntry) GetTenantID() string {
+	return o.Get(COLUMN_TENANT_ID)
+}
@@ -0,0 +1,199 @@
+package settingstore
+
+import "errors"
+
+type SettingAuditQueryInterface interface {
+	Validate() error
+
+	HasActor() bool
+	Actor() string
+	SetActor(actor string) SettingAuditQueryInterface
+
+	HasKeyPrefix() bool
+	KeyPrefix() string
+	SetKeyPrefix(keyPrefix string) SettingAuditQueryInterface
+
+	HasRequestID() bool
+	RequestID() string
+	SetRequestID(requestID string) SettingAuditQueryInterface
+
+	HasCreatedAtGte() bool
+	CreatedAtGte() string
+	SetCreatedAtGte(createdAtGte string) SettingAuditQueryInterface
+
+	HasCreatedAtLte() bool
+	CreatedAtLte() string
+	SetCreatedAtLte(createdAtLte string) SettingAuditQueryInterface
+
+	HasOffset() bool
+	Offset() int
+	SetOffset(offset int) SettingAuditQueryInterface
+
+	HasLimit() bool
+	Limit() int
+	SetLimit(limit int) SettingAuditQueryInterface
+
+	// SetSortOrder sorts the entries by time, newest first (desc) by default
+	HasSortOrder() bool
+	SortOrder() string
+	SetSortOrder(sortOrder string) SettingAuditQueryInterface
+}
+
+// SettingAuditQuery is a shortcut version of NewSettingAuditQuery to create a new query
+func SettingAuditQuery() SettingAuditQueryInterface {
+	return NewSettingAuditQuery()
+}
+
+// NewSettingAuditQuery creates a new setting audit query
+func NewSettingAuditQuery() SettingAuditQueryInterface {
+	return &settingAuditQuery{
+		properties: make(map[string]interface{}),
+	}
+}
+
+var _ SettingAuditQueryInterface = (*settingAuditQuery)(nil)
+
+type settingAuditQuery struct {
+	properties map[string]interface{}
+}
+
+func (q *settingAuditQuery) Validate() error {
+	if q.HasActor() && q.Actor() == "" {
+		return errors.New("Setting audit query. actor cannot be empty")
+	}
+
+	if q.HasKeyPrefix() && q.KeyPrefix() == "" {
+		return errors.New("Setting audit query. key_prefix cannot be empty")
+	}
+
+	if q.HasRequestID() && q.RequestID() == "" {
+		return errors.New("Setting audit query. request_id cannot be empty")
+	}
+
+	if q.HasCreatedAtGte() && q.CreatedAtGte() == "" {
+		return errors.New("Setting audit query. created_at_gte cannot be empty")
+	}
+
+	if q.HasCreatedAtLte() && q.CreatedAtLte() == "" {
+		return errors.New("Setting audit query. created_at_lte cannot be empty")
+	}
+
+	if q.HasLimit() && q.Limit() < 0 {
+		return errors.New("Setting audit query. limit cannot be negative")
+	}
+
+	if q.HasOffset() && q.Offset() < 0 {
+		return errors.New("Setting audit query. offset cannot be negative")
+	}
+
+	return nil
+}
+
+func (q *settingAuditQuery) HasActor() bool {
+	return q.hasProperty("actor")
+}
+
+func (q *settingAuditQuery) Actor() string {
+	return q.properties["actor"].(string)
+}
+
+func (q *settingAuditQuery) SetActor(actor string) SettingAuditQueryInterface {
+	q.properties["actor"] = actor
+	return q
+}
+
+func (q *settingAuditQuery) HasKeyPrefix() bool {
+	return q.hasProperty("key_prefix")
+}
+
+func (q *settingAuditQuery) KeyPrefix() string {
+	return q.properties["key_prefix"].(string)
+}
+
+func (q *settingAuditQuery) SetKeyPrefix(keyPrefix string) SettingAuditQueryInterface {
+	q.properties["key_prefix"] = keyPrefix
+	return q
+}
+
+func (q *settingAuditQuery) HasRequestID() bool {
+	return q.hasProperty("request_id")
+}
+
+func (q *settingAuditQuery) RequestID() string {
+	return q.properties["request_id"].(string)
+}
+
+func (q *settingAuditQuery) SetRequestID(requestID string) SettingAuditQueryInterface {
+	q.properties["request_id"] = requestID
+	return q
+}
+
+func (q *settingAuditQuery) HasCreatedAtGte() bool {
+	return q.hasProperty("created_at_gte")
+}
+
+func (q *settingAuditQuery) CreatedAtGte() string {
+	return q.properties["created_at_gte"].(string)
+}
+
+func (q *settingAuditQuery) SetCreatedAtGte(createdAtGte string) SettingAuditQueryInterface {
+	q.properties["created_at_gte"] = createdAtGte
+	return q
+}
+
+func (q *settingAuditQuery) HasCreatedAtLte() bool {
+	return q.hasProperty("created_at_lte")
+}
+
+func (q *settingAuditQuery) CreatedAtLte() string {
+	return q.properties["created_at_lte"].(string)
+}
+
+func (q *settingAuditQuery) SetCreatedAtLte(createdAtLte string) SettingAuditQueryInterface {
+	q.properties["created_at_lte"] = createdAtLte
+	return q
+}
+
+func (q *settingAuditQuery) HasLimit() bool {
+	return q.hasProperty("limit")
+}
+
+func (q *settingAuditQuery) Limit() int {
+	return q.properties["limit"].(int)
+}
+
+func (q *settingAuditQuery) SetLimit(limit int) SettingAuditQueryInterface {
+	q.properties["limit"] = limit
+	return q
+}
+
+func (q *settingAuditQuery) HasOffset() bool {
+	return q.hasProperty("offset")
+}
+
+func (q *settingAuditQuery) Offset() int {
+	return q.properties["offset"].(int)
+}
+
+func (q *settingAuditQuery) SetOffset(offset int) SettingAuditQueryInterface {
+	q.properties["offset"] = offset
+	return q
+}
+
+func (q *settingAuditQuery) HasSortOrder() bool {
+	return q.hasProperty("sort_order")
+}
+
+func (q *settingAuditQuery) SortOrder() string {
+	return q.properties["sort_order"].(string)
+}
+
+func (q *settingAuditQuery) SetSortOrder(sortOrder string) SettingAuditQueryInterface {
+	q.properties["sort_order"] = sortOrder
+	return q
+}
+
+func (q *settingAuditQuery) hasProperty(key string) bool {
+	_, ok := q.properties[key]
+	return ok
+}
@@ -0,0 +1,111 @@
+package settingstore
+
+import (
+	"strconv"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/dataobject"
+	"github.com/gouniverse/uid"
+)
+
+const (
+	HISTORY_ACTION_CREATE      = "create"
+	HISTORY_ACTION_UPDATE      = "update"
+	HISTORY_ACTION_SOFT_DELETE = "soft_delete"
+	HISTORY_ACTION_DELETE      = "delete"
+)
+
+// SettingHistoryEntryInterface is a recorded change of a setting
+type SettingHistoryEntryInterface interface {
+	Data() map[string]string
+
+	// IsDeletion returns true if the setting was deleted or soft deleted by the change
+	IsDeletion() bool
+
+	GetAction() string
+	GetCreatedAt() string
+	GetCreatedAtCarbon() *carbon.Carbon
+	GetID() string
+	GetKey() string
+	GetNewValue() string
+	GetOldValue() string
+	GetSettingID() string
+	GetTenantID() string
+	GetVersion() int64
+}
+
+var _ SettingHistoryEntryInterface = (*SettingHistoryEntry)(nil)
+
+// SettingHistoryEntry type
+type SettingHistoryEntry struct {
+	dataobject.DataObject
+}
+
+// == CONSTRUCTORS ============================================================
+
+func newSettingHistoryEntry() *SettingHistoryEntry {
+	o := &SettingHistoryEntry{}
+	o.Set(COLUMN_ID, uid.HumanUid())
+	o.Set(COLUMN_CREATED_AT, carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
+	return o
+}
+
+func NewSettingHistoryEntryFromExistingData(data map[string]string) SettingHistoryEntryInterface {
+	o := &SettingHistoryEntry{}
+	o.Hydrate(data)
+	return o
+}
+
+// == METHODS =================================================================
+
+func (o *SettingHistoryEntry) IsDeletion() bool {
+	return o.GetAction() == HISTORY_ACTION_DELETE || o.GetAction() == HISTORY_ACTION_SOFT_DELETE
+}
+
+// == GETTERS =================================================================
+
+// GetAction returns the change, one of the HISTORY_ACTION_* constants
+func (o *SettingHistoryEntry) GetAction() string {
+	return o.Get(COLUMN_ACTION)
+}
+
+func (o *SettingHistoryEntry) GetCreatedAt() string {
+	return o.Get(COLUMN_CREATED_AT)
+}
+
+func (o *SettingHistoryEntry) GetCreatedAtCarbon() *carbon.Carbon {
+	return carbon.Parse(o.GetCreatedAt(), carbon.UTC)
+}
+
+func (o *SettingHistoryEntry) GetID() string {
+	return o.Get(COLUMN_ID)
+}
+
+func (o *SettingHistoryEntry) GetKey() string {
+	return o.Get(COLUMN_SETTING_KEY)
+}
+
+// GetNewValue returns the value after the change, empty for a deletion
+func (o *SettingHistoryEntry) GetNewValue() string {
+	return o.Get(COLUMN_NEW_VALUE)
+}
+
+// GetOldValue returns the value before the change, empty for a creation
+func (o *SettingHistoryEntry) GetOldValue() string {
+	return o.Get(COLUMN_OLD_VALUE)
+}
+
+func (o *SettingHistoryEntry) GetSettingID() string {
+	return o.Get(COLUMN_SETTING_ID)
+}
+
+func (o *SettingHistoryEntry) GetTenantID() string {
+	return o.Get(COLUMN_TENANT_ID)
+}
+
+// GetVersion returns the number of the change among the changes of the key,
+// starting from 1
+func (o *SettingHistoryEntry) GetVersion() int64 {
+	version, _ := strconv.ParseInt(o.Get(COLUMN_VERSION), 10, 64)
+	return version
+}
@@ -0,0 +1,136 @@
+package settingstore
+
+import "errors"
+
+type SettingHistoryQueryInterface interface {
+	Validate() error
+
+	HasCreatedAtGte() bool
+	CreatedAtGte() string
+	SetCreatedAtGte(createdAtGte string) SettingHistoryQueryInterface
+
+	HasCreatedAtLte() bool
+	CreatedAtLte() string
+	SetCreatedAtLte(createdAtLte string) SettingHistoryQueryInterface
+
+	HasOffset() bool
+	Offset() int
+	SetOffset(offset int) SettingHistoryQueryInterface
+
+	HasLimit() bool
+	Limit() int
+	SetLimit(limit int) SettingHistoryQueryInterface
+
+	// SetSortOrder sorts the entries by time, newest first (desc) by default
+	HasSortOrder() bool
+	SortOrder() string
+	SetSortOrder(sortOrder string) SettingHistoryQueryInterface
+}
+
+// SettingHistoryQuery is a shortcut version of NewSettingHistoryQuery to create a new query
+func SettingHistoryQuery() SettingHistoryQueryInterface {
+	return NewSettingHistoryQuery()
+}
+
+// NewSettingHistoryQuery creates a new setting history query
+func NewSettingHistoryQuery() SettingHistoryQueryInterface {
+	return &settingHistoryQuery{
+		properties: make(map[string]interface{}),
+	}
+}
+
+var _ SettingHistoryQueryInterface = (*settingHistoryQuery)(nil)
+
+type settingHistoryQuery struct {
+	properties map[string]interface{}
+}
+
+func (q *settingHistoryQuery) Validate() error {
+	if q.HasCreatedAtGte() && q.CreatedAtGte() == "" {
+		return errors.New("Setting history query. created_at_gte cannot be empty")
+	}
+
+	if q.HasCreatedAtLte() && q.CreatedAtLte() == "" {
+		return errors.New("Setting history query. created_at_lte cannot be empty")
+	}
+
+	if q.HasLimit() && q.Limit() < 0 {
+		return errors.New("Setting history query. limit cannot be negative")
+	}
+
+	if q.HasOffset() && q.Offset() < 0 {
+		return errors.New("Setting history query. offset cannot be negative")
+	}
+
+	return nil
+}
+
+func (q *settingHistoryQuery) HasCreatedAtGte() bool {
+	return q.hasProperty("created_at_gte")
+}
+
+func (q *settingHistoryQuery) CreatedAtGte() string {
+	return q.properties["created_at_gte"].(string)
+}
+
+func (q *settingHistoryQuery) SetCreatedAtGte(createdAtGte string) SettingHistoryQueryInterface {
+	q.properties["created_at_gte"] = createdAtGte
+	return q
+}
+
+func (q *settingHistoryQuery) HasCreatedAtLte() bool {
+	return q.hasProperty("created_at_lte")
+}
+
+func (q *settingHistoryQuery) CreatedAtLte() string {
+	return q.properties["created_at_lte"].(string)
+}
+
+func (q *settingHistoryQuery) SetCreatedAtLte(createdAtLte string) SettingHistoryQueryInterface {
+	q.properties["created_at_lte"] = createdAtLte
+	return q
+}
+
+func (q *settingHistoryQuery) HasLimit() bool {
+	return q.hasProperty("limit")
+}
+
+func (q *settingHistoryQuery) Limit() int {
+	return q.properties["limit"].(int)
+}
+
+func (q *settingHistoryQuery) SetLimit(limit int) SettingHistoryQueryInterface {
+	q.properties["limit"] = limit
+	return q
+}
+
+func (q *settingHistoryQuery) HasOffset() bool {
+	return q.hasProperty("offset")
+}
+
+func (q *settingHistoryQuery) Offset() int {
+	return q.properties["offset"].(int)
+}
+
+func (q *settingHistoryQuery) SetOffset(offset int) SettingHistoryQueryInterface {
+	q.properties["offset"] = offset
+	return q
+}
+
+func (q *settingHistoryQuery) HasSortOrder() bool {
+	return q.hasProperty("sort_order")
+}
+
+func (q *settingHistoryQuery) SortOrder() string {
+	return q.properties["sort_order"].(string)
+}
+
+func (q *settingHistoryQuery) SetSortOrder(sortOrder string) SettingHistoryQueryInterface {
+	q.properties["sort_order"] = sortOrder
+	return q
+}
+
+func (q *settingHistoryQuery) hasProperty(key string) bool {
+	_, ok := q.properties[key]
+	return ok
+}
@@ -11,7 +11,10 @@ type SettingInterface interface {
 
 	// Methods
 
+	IsExpired() bool
+	IsSensitive() bool
 	IsSoftDeleted() bool
+	SetSensitive(sensitive bool) SettingInterface
 
 	// Setters and Getters
 
@@ -19,6 +22,10 @@ type SettingInterface interface {
 	GetCreatedAtCarbon() *carbon.Carbon
 	SetCreatedAt(createdAt string) SettingInterface
 
+	GetExpiresAt() string
+	GetExpiresAtCarbon() *carbon.Carbon
+	SetExpiresAt(expiresAt string) SettingInterface
+
 	GetID() string
 	SetID(id string) SettingInterface
 
@@ -29,10 +36,16 @@ type SettingInterface interface {
 	GetSoftDeletedAtCarbon() *carbon.Carbon
 	SetSoftDeletedAt(deletedAt string) SettingInterface
 
+	GetTenantID() string
+	SetTenantID(tenantID string) SettingInterface
+
 	GetUpdatedAt() string
 	GetUpdatedAtCarbon() *carbon.Carbon
 	SetUpdatedAt(updatedAt string) SettingInterface
 
 	GetValue() string
 	SetValue(value string) SettingInterface
+
+	GetVersion() int64
+	SetVersion(version int64) SettingInterface
 }
@@ -18,6 +18,10 @@ type SettingQueryInterface interface {
 	CreatedAtLte() string
 	SetCreatedAtLte(createdAtLte string) SettingQueryInterface
 
+	HasExpiredIncluded() bool
+	ExpiredIncluded() bool
+	SetExpiredIncluded(expiredIncluded bool) SettingQueryInterface
+
 	HasID() bool
 	ID() string
 	SetID(id string) SettingQueryInterface
@@ -30,6 +34,22 @@ type SettingQueryInterface interface {
 	Key() string
 	SetKey(key string) SettingQueryInterface
 
+	HasKeyIn() bool
+	KeyIn() []string
+	SetKeyIn(keyIn []string) SettingQueryInterface
+
+	// SetKeyLike filters the keys matching the pattern, where * matches
+	// any sequence of characters and ? matches a single character. Any
+	// % and _ in the pattern are matched literally
+	HasKeyLike() bool
+	KeyLike() string
+	SetKeyLike(keyLike string) SettingQueryInterface
+
+	// SetKeyPrefix filters the keys starting with the prefix, i.e. "mail.smtp."
+	HasKeyPrefix() bool
+	KeyPrefix() string
+	SetKeyPrefix(keyPrefix string) SettingQueryInterface
+
 	HasOffset() bool
 	Offset() int
 	SetOffset(offset int) SettingQueryInterface
@@ -52,6 +72,17 @@ type SettingQueryInterface interface {
 	HasSoftDeletedIncluded() bool
 	SoftDeletedIncluded() bool
 	SetSoftDeletedIncluded(withSoftDeleted bool) SettingQueryInterface
+
+	// SetTenantID filters the settings of the tenant, an empty ID being the
+	// settings which belong to no tenant. A store scoped with ForTenant only
+	// ever sees its own tenant, whatever the filter
+	HasTenantID() bool
+	TenantID() string
+	SetTenantID(tenantID string) SettingQueryInterface
+
+	HasUpdatedAtGte() bool
+	UpdatedAtGte() string
+	SetUpdatedAtGte(updatedAtGte string) SettingQueryInterface
 }
 
 // SettingQuery is a shortcut version of NewSettingQuery to create a new query
@@ -81,6 +112,10 @@ func (q *settingQuery) Validate() error {
 		return errors.New("Setting query. created_at_lte cannot be empty")
 	}
 
+	if q.HasUpdatedAtGte() && q.UpdatedAtGte() == "" {
+		return errors.New("Setting query. updated_at_gte cannot be empty")
+	}
+
 	if q.HasID() && q.ID() == "" {
 		return errors.New("Setting query. id cannot be empty")
 	}
@@ -93,6 +128,18 @@ func (q *settingQuery) Validate() error {
 		return errors.New("Setting query. key cannot be empty")
 	}
 
+	if q.HasKeyIn() && len(q.KeyIn()) < 1 {
+		return errors.New("Setting query. key_in cannot be empty array")
+	}
+
+	if q.HasKeyLike() && q.KeyLike() == "" {
+		return errors.New("Setting query. key_like cannot be empty")
+	}
+
+	if q.HasKeyPrefix() && q.KeyPrefix() == "" {
+		return errors.New("Setting query. key_prefix cannot be empty")
+	}
+
 	if q.HasLimit() && q.Limit() < 0 {
 		return errors.New("Setting query. limit cannot be negative")
 	}
@@ -156,6 +203,23 @@ func (q *settingQuery) SetCreatedAtLte(createdAtLte string) SettingQueryInterfac
 	return q
 }
 
+func (q *settingQuery) HasExpiredIncluded() bool {
+	return q.hasProperty("expired_included")
+}
+
+func (q *settingQuery) ExpiredIncluded() bool {
+	if !q.HasExpiredIncluded() {
+		return false
+	}
+
+	return q.properties["expired_included"].(bool)
+}
+
+func (q *settingQuery) SetExpiredIncluded(expiredIncluded bool) SettingQueryInterface {
+	q.properties["expired_included"] = expiredIncluded
+	return q
+}
+
 func (q *settingQuery) HasID() bool {
 	return q.hasProperty("id")
 }
@@ -195,6 +259,45 @@ func (q *settingQuery) SetKey(key string) SettingQueryInterface {
 	return q
 }
 
+func (q *settingQuery) HasKeyIn() bool {
+	return q.hasProperty("key_in")
+}
+
+func (q *settingQuery) KeyIn() []string {
+	return q.properties["key_in"].([]string)
+}
+
+func (q *settingQuery) SetKeyIn(keyIn []string) SettingQueryInterface {
+	q.properties["key_in"] = keyIn
+	return q
+}
+
+func (q *settingQuery) HasKeyLike() bool {
+	return q.hasProperty("key_like")
+}
+
+func (q *settingQuery) KeyLike() string {
+	return q.properties["key_like"].(string)
+}
+
+func (q *settingQuery) SetKeyLike(keyLike string) SettingQueryInterface {
+	q.properties["key_like"] = keyLike
+	return q
+}
+
+func (q *settingQuery) HasKeyPrefix() bool {
+	return q.hasProperty("key_prefix")
+}
+
+func (q *settingQuery) KeyPrefix() string {
+	return q.properties["key_prefix"].(string)
+}
+
+func (q *settingQuery) SetKeyPrefix(keyPrefix string) SettingQueryInterface {
+	q.properties["key_prefix"] = keyPrefix
+	return q
+}
+
 func (q *settingQuery) HasLimit() bool {
 	return q.hasProperty("limit")
 }
@@ -264,6 +367,32 @@ func (q *settingQuery) SetSortOrder(sortOrder string) SettingQueryInterface {
 	return q
 }
 
+func (q *settingQuery) HasTenantID() bool {
+	return q.hasProperty("tenant_id")
+}
+
+func (q *settingQuery) TenantID() string {
+	return q.properties["tenant_id"].(string)
+}
+
+func (q *settingQuery) SetTenantID(tenantID string) SettingQueryInterface {
+	q.properties["tenant_id"] = tenantID
+	return q
+}
+
+func (q *settingQuery) HasUpdatedAtGte() bool {
+	return q.hasProperty("updated_at_gte")
+}
+
+func (q *settingQuery) UpdatedAtGte() string {
+	return q.properties["updated_at_gte"].(string)
+}
+
+func (q *settingQuery) SetUpdatedAtGte(updatedAtGte string) SettingQueryInterface {
+	q.properties["updated_at_gte"] = updatedAtGte
+	return q
+}
+
 func (q *settingQuery) hasProperty(key string) bool {
 	_, ok := q.properties[key]
 	return ok
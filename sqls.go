@@ -1,41 +1,156 @@
 package settingstore
 
 import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
 )
 
 // SQLCreateTable returns a SQL string for creating the cache table
 func (store *store) SQLCreateTable() string {
-	sql := sb.NewBuilder(store.dbDriverName).
-		Table(store.settingTableName).
-		Column(sb.Column{
+	builder := sb.NewBuilder(store.dbDriverName).
+		Table(store.settingTableName)
+
+	for _, column := range store.settingTableColumns() {
+		builder = builder.Column(column)
+	}
+
+	return builder.CreateIfNotExists()
+}
+
+// settingTableColumns returns the columns of the settings table
+func (store *store) settingTableColumns() []sb.Column {
+	return []sb.Column{
+		{
 			Name:       COLUMN_ID,
 			Type:       sb.COLUMN_TYPE_STRING,
 			Length:     40,
 			PrimaryKey: true,
-		}).
-		Column(sb.Column{
+		},
+		{
+			Name:   COLUMN_TENANT_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		},
+		{
 			Name:   COLUMN_SETTING_KEY,
 			Type:   sb.COLUMN_TYPE_STRING,
 			Length: 255,
-		}).
-		Column(sb.Column{
+		},
+		{
 			Name: COLUMN_SETTING_VALUE,
 			Type: sb.COLUMN_TYPE_TEXT,
-		}).
-		Column(sb.Column{
+		},
+		{
 			Name: COLUMN_CREATED_AT,
 			Type: sb.COLUMN_TYPE_DATETIME,
-		}).
-		Column(sb.Column{
+		},
+		{
 			Name: COLUMN_UPDATED_AT,
 			Type: sb.COLUMN_TYPE_DATETIME,
-		}).
-		Column(sb.Column{
+		},
+		{
+			Name: COLUMN_EXPIRES_AT,
+			Type: sb.COLUMN_TYPE_DATETIME,
+		},
+		{
 			Name: COLUMN_SOFT_DELETED_AT,
 			Type: sb.COLUMN_TYPE_DATETIME,
-		}).
-		CreateIfNotExists()
+		},
+		{
+			Name: COLUMN_VERSION,
+			Type: sb.COLUMN_TYPE_INTEGER,
+		},
+	}
+}
+
+// settingTableColumnDefaults returns the values used to backfill
+// columns which were added to an existing settings table
+func (store *store) settingTableColumnDefaults() map[string]string {
+	return map[string]string{
+		COLUMN_EXPIRES_AT: sb.MAX_DATETIME,
+		COLUMN_TENANT_ID:  "", // the existing settings belong to no tenant
+		COLUMN_VERSION:    "1",
+	}
+}
+
+// liveKeyPredicate is the condition of the rows covered by the unique
+// index of the keys, the settings which are not soft deleted
+const liveKeyPredicate = COLUMN_SOFT_DELETED_AT + " = '" + sb.MAX_DATETIME + "'"
+
+// keyIndexName returns the name of the unique index of the keys
+func (store *store) keyIndexName() string {
+	return store.settingTableName + "_key_unique"
+}
+
+// sqlCreateKeyIndex returns a SQL string for creating the unique index
+// of the keys, one live setting per key and tenant
+//
+// MySQL has no partial indexes, so the soft deletion time is part of
+// the index instead. There a key soft deleted twice in the same second
+// conflicts with itself
+func (store *store) sqlCreateKeyIndex() string {
+	if store.dbDriverName == sb.DIALECT_MYSQL {
+		return sqlCreateUniqueIndex(store.dbDriverName, store.keyIndexName(), store.settingTableName,
+			[]string{COLUMN_TENANT_ID, COLUMN_SETTING_KEY, COLUMN_SOFT_DELETED_AT}, "")
+	}
+
+	return sqlCreateUniqueIndex(store.dbDriverName, store.keyIndexName(), store.settingTableName,
+		[]string{COLUMN_TENANT_ID, COLUMN_SETTING_KEY}, liveKeyPredicate)
+}
+
+// sqlCreateUniqueIndex returns a SQL string for creating a unique index
+// of the columns, partial if the predicate is not empty
+func sqlCreateUniqueIndex(dialect string, indexName string, tableName string, columns []string, predicate string) string {
+	quotedColumns := lo.Map(columns, func(column string, _ int) string {
+		return quoteIdentifier(dialect, column)
+	})
+
+	sqlStr := "CREATE UNIQUE INDEX " + quoteIdentifier(dialect, indexName) +
+		" ON " + quoteIdentifier(dialect, tableName) +
+		" (" + strings.Join(quotedColumns, ", ") + ")"
+
+	if predicate == "" {
+		return sqlStr
+	}
+
+	return sqlStr + " WHERE " + predicate
+}
+
+// quoteIdentifier quotes the name of a table, column or index
+func quoteIdentifier(dialect string, name string) string {
+	switch dialect {
+	case sb.DIALECT_MYSQL:
+		return "`" + name + "`"
+	case sb.DIALECT_MSSQL, "sqlserver":
+		return "[" + name + "]"
+	}
+
+	return `"` + name + `"`
+}
+
+// versionIncrementExpression returns the new version of an updated row
+func versionIncrementExpression() exp.Expression {
+	return goqu.L("? + 1", goqu.C(COLUMN_VERSION))
+}
+
+// keyLikeExpression returns the condition matching the keys
+// against the LIKE pattern, escaped with likeEscape
+func keyLikeExpression(pattern string) exp.Expression {
+	return goqu.L("? LIKE ? ESCAPE '!'", goqu.C(COLUMN_SETTING_KEY), pattern)
+}
 
-	return sql
+// keyPrefixExpression returns the condition matching the keys starting
+// with the prefix. The LIKE may use the index of the key column, while
+// the SUBSTR comparison keeps the match case sensitive on the databases
+// where LIKE is not (i.e. SQLite)
+func keyPrefixExpression(prefix string) exp.Expression {
+	return goqu.And(
+		keyLikeExpression(likeEscape(prefix)+"%"),
+		goqu.L("SUBSTR(?, 1, ?) = ?", goqu.C(COLUMN_SETTING_KEY), utf8.RuneCountInString(prefix), prefix),
+	)
 }
@@ -0,0 +1,245 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+var errAuditNotEnabled = errors.New("settingstore: audit is not enabled, set AuditTableName")
+
+// SQLCreateAuditTable returns a SQL string for creating the audit table
+func (store *store) SQLCreateAuditTable() string {
+	builder := sb.NewBuilder(store.dbDriverName).
+		Table(store.auditTableName)
+
+	for _, column := range store.auditTableColumns() {
+		builder = builder.Column(column)
+	}
+
+	return builder.CreateIfNotExists()
+}
+
+// auditTableColumns returns the columns of the audit table
+func (store *store) auditTableColumns() []sb.Column {
+	return []sb.Column{
+		{
+			Name:       COLUMN_ID,
+			Type:       sb.COLUMN_TYPE_STRING,
+			Length:     40,
+			PrimaryKey: true,
+		},
+		{
+			Name:   COLUMN_SEQUENCE,
+			Type:   sb.COLUMN_TYPE_INTEGER,
+			Unique: true,
+		},
+		{
+			Name:   COLUMN_SETTING_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		},
+		{
+			Name:   COLUMN_TENANT_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		},
+		{
+			Name:   COLUMN_SETTING_KEY,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 255,
+		},
+		{
+			Name:   COLUMN_ACTION,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 20,
+		},
+		{
+			Name: COLUMN_OLD_VALUE,
+			Type: sb.COLUMN_TYPE_TEXT,
+		},
+		{
+			Name: COLUMN_NEW_VALUE,
+			Type: sb.COLUMN_TYPE_TEXT,
+		},
+		{
+			Name:   COLUMN_ACTOR,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 255,
+		},
+		{
+			Name: COLUMN_REASON,
+			Type: sb.COLUMN_TYPE_TEXT,
+		},
+		{
+			Name:   COLUMN_REQUEST_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 255,
+		},
+		{
+			Name: COLUMN_CREATED_AT,
+			Type: sb.COLUMN_TYPE_DATETIME,
+		},
+		{
+			Name:   COLUMN_PREVIOUS_HASH,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 64,
+		},
+		{
+			Name:   COLUMN_HASH,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 64,
+		},
+	}
+}
+
+// SettingAudit returns the changes recorded in the audit trail
+//
+// Parameters:
+// - ctx: the context
+// - query: the query to filter and page the entries, nil for all of them
+//
+// Returns:
+// - []SettingAuditEntryInterface - the entries, newest first unless sorted otherwise
+// - error - nil if no error, error otherwise
+func (store *store) SettingAudit(ctx context.Context, query SettingAuditQueryInterface) ([]SettingAuditEntryInterface, error) {
+	if store.auditTableName == "" {
+		return nil, errAuditNotEnabled
+	}
+
+	if query == nil {
+		query = SettingAuditQuery()
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	q := goqu.Dialect(store.dbDriverName).
+		From(store.auditTableName).
+		Prepared(true).
+		Where(store.tenantExpression()).
+		Where(store.namespaceExpression())
+
+	if query.HasActor() {
+		q = q.Where(goqu.C(COLUMN_ACTOR).Eq(query.Actor()))
+	}
+
+	if query.HasKeyPrefix() {
+		q = q.Where(keyPrefixExpression(store.namespacedKey(query.KeyPrefix())))
+	}
+
+	if query.HasRequestID() {
+		q = q.Where(goqu.C(COLUMN_REQUEST_ID).Eq(query.RequestID()))
+	}
+
+	if query.HasCreatedAtGte() {
+		q = q.Where(goqu.C(COLUMN_CREATED_AT).Gte(query.CreatedAtGte()))
+	}
+
+	if query.HasCreatedAtLte() {
+		q = q.Where(goqu.C(COLUMN_CREATED_AT).Lte(query.CreatedAtLte()))
+	}
+
+	// the sequence orders the entries of the same second
+	if query.HasSortOrder() && strings.EqualFold(query.SortOrder(), sb.ASC) {
+		q = q.Order(goqu.C(COLUMN_SEQUENCE).Asc())
+	} else {
+		q = q.Order(goqu.C(COLUMN_SEQUENCE).Desc())
+	}
+
+	if query.HasLimit() {
+		q = q.Limit(uint(query.Limit()))
+	}
+
+	if query.HasOffset() {
+		q = q.Offset(uint(query.Offset()))
+	}
+
+	sqlStr, params, errSql := q.ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("audit", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(rows, func(row map[string]string, _ int) SettingAuditEntryInterface {
+		if store.isSensitiveKey(row[COLUMN_SETTING_KEY]) {
+			redactRow(row, COLUMN_OLD_VALUE, COLUMN_NEW_VALUE)
+		}
+
+		row[COLUMN_SETTING_KEY] = store.namespaceStrip(row[COLUMN_SETTING_KEY])
+		return NewSettingAuditEntryFromExistingData(row)
+	}), nil
+}
+
+// migrateAuditTable creates the audit table if it does not exist
+func (store *store) migrateAuditTable(ctx context.Context) error {
+	sqlStr := store.SQLCreateAuditTable()
+
+	if sqlStr == "" {
+		return errors.New("setting store: audit table create sql is empty")
+	}
+
+	_, err := database.Execute(database.Context(ctx, store.db), sqlStr)
+
+	return err
+}
+
+// auditRecord inserts the changes into the audit table, with the audit
+// metadata carried by the context, chained to the last entry, in the
+// transaction the store is bound to. It does nothing if the audit is not
+// enabled
+func (st *store) auditRecord(ctx context.Context, changes ...recordedChange) error {
+	if st.auditTableName == "" {
+		return nil
+	}
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		return txStore.auditInsert(ctx, changes...)
+	})
+}
+
+// auditInsert inserts the changes into the audit table, in the transaction
+// the store is bound to
+func (store *store) auditInsert(ctx context.Context, changes ...recordedChange) error {
+	metadata := AuditMetadataFromContext(ctx)
+
+	for _, change := range changes {
+		entry := newSettingAuditEntry()
+		entry.Set(COLUMN_SETTING_ID, change.settingID)
+		entry.Set(COLUMN_TENANT_ID, change.tenantID)
+		entry.Set(COLUMN_SETTING_KEY, change.key)
+		entry.Set(COLUMN_ACTION, change.action)
+		entry.Set(COLUMN_OLD_VALUE, change.oldValue)
+		entry.Set(COLUMN_NEW_VALUE, change.newValue)
+		entry.Set(COLUMN_ACTOR, metadata.Actor)
+		entry.Set(COLUMN_REASON, metadata.Reason)
+		entry.Set(COLUMN_REQUEST_ID, metadata.RequestID)
+
+		if change.createdAt != "" {
+			entry.Set(COLUMN_CREATED_AT, change.createdAt)
+		} else {
+			entry.Set(COLUMN_CREATED_AT, carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
+		}
+
+		if err := store.auditInsertLinked(ctx, entry, change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package settingstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// auditChainBatchSize is the number of entries VerifyAuditChain loads at once
+const auditChainBatchSize = 1000
+
+// auditLinkAttempts is the number of times an audit entry is chained
+// again, when the sequence it was chained with is taken concurrently
+const auditLinkAttempts = 10
+
+// auditHashedColumns are the columns of an audit entry covered by its hash
+var auditHashedColumns = []string{
+	COLUMN_SEQUENCE,
+	COLUMN_PREVIOUS_HASH,
+	COLUMN_ID,
+	COLUMN_SETTING_ID,
+	COLUMN_TENANT_ID,
+	COLUMN_SETTING_KEY,
+	COLUMN_ACTION,
+	COLUMN_OLD_VALUE,
+	COLUMN_NEW_VALUE,
+	COLUMN_ACTOR,
+	COLUMN_REASON,
+	COLUMN_REQUEST_ID,
+	COLUMN_CREATED_AT,
+}
+
+// AuditChainError reports the first broken link of the audit chain
+type AuditChainError struct {
+	// Sequence is the position in the chain where the link is broken
+	Sequence int64
+
+	// EntryID is the ID of the entry failing the verification,
+	// empty if the entry is missing
+	EntryID string
+
+	// Reason describes how the link is broken
+	Reason string
+}
+
+// Error implements the error interface
+func (e *AuditChainError) Error() string {
+	if e.EntryID == "" {
+		return fmt.Sprintf("settingstore: audit chain broken at sequence %d: %s", e.Sequence, e.Reason)
+	}
+
+	return fmt.Sprintf("settingstore: audit chain broken at sequence %d (entry %s): %s", e.Sequence, e.EntryID, e.Reason)
+}
+
+// VerifyAuditChain walks the whole audit trail, of all the tenants, and
+// checks that every entry is unchanged and chained to the previous one
+//
+// The removal of the newest entries leaves a valid chain, keep the hash
+// of the last entry elsewhere to detect it
+//
+// Parameters:
+// - ctx: the context
+//
+// Returns:
+// - error - nil if the chain is intact, *AuditChainError for the first
+// broken link, error otherwise
+func (store *store) VerifyAuditChain(ctx context.Context) error {
+	if store.auditTableName == "" {
+		return errAuditNotEnabled
+	}
+
+	lastSequence := int64(0)
+	lastHash := ""
+
+	for {
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			From(store.auditTableName).
+			Prepared(true).
+			Where(goqu.C(COLUMN_SEQUENCE).Gt(lastSequence)).
+			Order(goqu.C(COLUMN_SEQUENCE).Asc()).
+			Limit(auditChainBatchSize).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("audit", sqlStr, params...)
+
+		rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			entry := NewSettingAuditEntryFromExistingData(row)
+
+			if entry.GetSequence() != lastSequence+1 {
+				return &AuditChainError{Sequence: lastSequence + 1, Reason: "entry is missing"}
+			}
+
+			if entry.GetPreviousHash() != lastHash {
+				return &AuditChainError{Sequence: entry.GetSequence(), EntryID: entry.GetID(), Reason: "previous hash does not match the hash of the previous entry"}
+			}
+
+			if !hmac.Equal([]byte(entry.GetHash()), []byte(store.auditHash(row))) {
+				return &AuditChainError{Sequence: entry.GetSequence(), EntryID: entry.GetID(), Reason: "hash does not match the content of the entry"}
+			}
+
+			lastSequence = entry.GetSequence()
+			lastHash = entry.GetHash()
+		}
+
+		if len(rows) < auditChainBatchSize {
+			break
+		}
+	}
+
+	unlinked, err := store.auditUnlinkedEntries(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if len(unlinked) > 0 {
+		return &AuditChainError{Sequence: lastSequence + 1, EntryID: unlinked[0][COLUMN_ID], Reason: "entry is not chained"}
+	}
+
+	return nil
+}
+
+// auditHash returns the hash of the audit entry, HMAC-SHA256 if the store
+// has an audit key, SHA-256 otherwise
+func (store *store) auditHash(data map[string]string) string {
+	values := lo.Map(auditHashedColumns, func(column string, _ int) string {
+		if column == COLUMN_CREATED_AT {
+			// read back in the format of the driver
+			return carbon.Parse(data[column], carbon.UTC).ToDateTimeString(carbon.UTC)
+		}
+
+		return data[column]
+	})
+
+	// a JSON array keeps the boundaries of the values unambiguous
+	content, _ := json.Marshal(values)
+
+	var hasher hash.Hash
+
+	if len(store.auditHMACKey) > 0 {
+		hasher = hmac.New(sha256.New, store.auditHMACKey)
+	} else {
+		hasher = sha256.New()
+	}
+
+	hasher.Write(content)
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// auditInsertLinked chains the entry to the last entry of the audit trail,
+// and inserts it
+//
+// The sequence is unique, so of two transactions chaining to the same
+// entry at the same time, the insert of the second one fails instead of
+// forking the chain. It is rolled back to a savepoint then, and the entry
+// is chained again to the new last entry. Without savepoints, the insert
+// fails
+func (st *store) auditInsertLinked(ctx context.Context, entry *SettingAuditEntry, change recordedChange) error {
+	_, _, _, errSavepoint := savepointSqls(st.dbDriverName, "audit")
+
+	for attempt := 0; attempt < auditLinkAttempts; attempt++ {
+		sequence, err := st.auditLink(ctx, entry)
+
+		if err != nil {
+			return err
+		}
+
+		sqlStr, params, errSql := goqu.Dialect(st.dbDriverName).
+			Insert(st.auditTableName).
+			Prepared(true).
+			Rows(entry.Data()).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		st.logSql("audit", sqlStr, st.redactParams(params, change.key, change.oldValue, change.newValue)...)
+
+		if errSavepoint != nil {
+			_, err := st.executeSql(ctx, sqlStr, params...)
+			return err
+		}
+
+		errInsert := st.inSavepoint(ctx, func(txStore *store) error {
+			_, err := txStore.executeSql(ctx, sqlStr, params...)
+			return err
+		})
+
+		if errInsert == nil {
+			return nil
+		}
+
+		// chained again only if the sequence was taken concurrently
+		lastSequence, _, err := st.auditLastLink(ctx)
+
+		if err != nil {
+			return errors.Join(errInsert, err)
+		}
+
+		if lastSequence < sequence {
+			return errInsert
+		}
+	}
+
+	return ErrConflict
+}
+
+// auditLink chains the new audit entry to the last entry of the audit
+// trail, and returns the sequence it was given
+func (store *store) auditLink(ctx context.Context, entry *SettingAuditEntry) (int64, error) {
+	sequence, previousHash, err := store.auditLastLink(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	entry.Set(COLUMN_SEQUENCE, strconv.FormatInt(sequence+1, 10))
+	entry.Set(COLUMN_PREVIOUS_HASH, previousHash)
+	entry.Set(COLUMN_HASH, store.auditHash(entry.Data()))
+
+	return sequence + 1, nil
+}
+
+// auditLastLink returns the sequence and the hash of the last entry
+// of the audit trail, zero and empty if there are no entries
+//
+// On MySQL and Postgres the entry is locked, so that the transactions
+// chaining to it wait for each other, and read the last committed entry
+// instead of the snapshot of the transaction
+func (store *store) auditLastLink(ctx context.Context) (int64, string, error) {
+	query := goqu.Dialect(store.dbDriverName).
+		From(store.auditTableName).
+		Prepared(true).
+		Select(COLUMN_SEQUENCE, COLUMN_HASH).
+		Order(goqu.C(COLUMN_SEQUENCE).Desc()).
+		Limit(1)
+
+	if lo.Contains([]string{sb.DIALECT_MYSQL, sb.DIALECT_POSTGRES}, store.dbDriverName) {
+		query = query.ForUpdate(exp.Wait)
+	}
+
+	sqlStr, params, errSql := query.ToSQL()
+
+	if errSql != nil {
+		return 0, "", errSql
+	}
+
+	store.logSql("audit", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	if len(rows) < 1 {
+		return 0, "", nil
+	}
+
+	sequence, err := strconv.ParseInt(rows[0][COLUMN_SEQUENCE], 10, 64)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	return sequence, rows[0][COLUMN_HASH], nil
+}
+
+// auditUnlinkedEntries returns the entries outside of the chain, without
+// a sequence or a hash, in the order they were recorded. The store never
+// records such entries, so they were inserted directly in the database
+func (store *store) auditUnlinkedEntries(ctx context.Context) ([]map[string]string, error) {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.auditTableName).
+		Prepared(true).
+		Where(goqu.Or(
+			goqu.C(COLUMN_SEQUENCE).IsNull(),
+			goqu.C(COLUMN_SEQUENCE).Lt(1),
+			goqu.C(COLUMN_HASH).IsNull(),
+			goqu.C(COLUMN_HASH).Eq(""),
+		)).
+		Order(goqu.C(COLUMN_CREATED_AT).Asc(), goqu.C(COLUMN_ID).Asc()).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("audit", sqlStr, params...)
+
+	return store.selectToMapString(ctx, sqlStr, params...)
+}
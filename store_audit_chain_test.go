@@ -0,0 +1,263 @@
+package settingstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func initAuditChainStore(t *testing.T, hmacKey []byte) (*sql.DB, StoreInterface) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AuditTableName:     "setting_audit",
+		AuditHMACKey:       hmacKey,
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := WithActor(context.Background(), "alice")
+
+	for _, value := range []string{"a", "b", "c"} {
+		if err := store.Set(ctx, "app.name", value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if err := store.ForTenant("acme").Set(ctx, "app.name", "d"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return db, store
+}
+
+func TestStoreVerifyAuditChain(t *testing.T) {
+	_, store := initAuditChainStore(t, nil)
+
+	if err := store.VerifyAuditChain(context.Background()); err != nil {
+		t.Fatal("Chain MUST be intact, found:", err)
+	}
+
+	entries, err := store.SettingAudit(context.Background(), SettingAuditQuery().SetSortOrder("asc"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for i, entry := range entries {
+		if entry.GetSequence() != int64(i+1) {
+			t.Fatal("Sequences MUST be consecutive, found:", entry.GetSequence())
+		}
+
+		if i > 0 && entry.GetPreviousHash() != entries[i-1].GetHash() {
+			t.Fatal("Entry MUST be chained to the previous one")
+		}
+	}
+}
+
+func TestStoreVerifyAuditChainEdited(t *testing.T) {
+	db, store := initAuditChainStore(t, nil)
+
+	if _, err := db.Exec(`UPDATE setting_audit SET new_value = 'x' WHERE sequence = 2`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err := store.VerifyAuditChain(context.Background())
+
+	chainErr := &AuditChainError{}
+
+	if !errors.As(err, &chainErr) {
+		t.Fatal("Edited entry MUST break the chain, found:", err)
+	}
+
+	if chainErr.Sequence != 2 || chainErr.EntryID == "" {
+		t.Fatal("Chain MUST break at the edited entry, found:", chainErr)
+	}
+}
+
+func TestStoreVerifyAuditChainRemoved(t *testing.T) {
+	db, store := initAuditChainStore(t, nil)
+
+	if _, err := db.Exec(`DELETE FROM setting_audit WHERE sequence = 3`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err := store.VerifyAuditChain(context.Background())
+
+	chainErr := &AuditChainError{}
+
+	if !errors.As(err, &chainErr) || chainErr.Sequence != 3 || chainErr.EntryID != "" {
+		t.Fatal("Removed entry MUST break the chain at its sequence, found:", err)
+	}
+}
+
+func TestStoreVerifyAuditChainRehashed(t *testing.T) {
+	db, st := initAuditChainStore(t, []byte("secret"))
+
+	ctx := context.Background()
+
+	entries, err := st.SettingAudit(ctx, SettingAuditQuery().SetSortOrder("asc").SetLimit(1))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// an edit with the hash recomputed, without the key
+	data := entries[0].Data()
+	data[COLUMN_NEW_VALUE] = "x"
+
+	keyless := &store{}
+
+	if _, err := db.Exec(`UPDATE setting_audit SET new_value = ?, hash = ? WHERE sequence = 1`, "x", keyless.auditHash(data)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	err = st.VerifyAuditChain(ctx)
+
+	chainErr := &AuditChainError{}
+
+	if !errors.As(err, &chainErr) || chainErr.Sequence != 1 {
+		t.Fatal("Rehashed entry MUST break the chain, found:", err)
+	}
+}
+
+func TestStoreVerifyAuditChainUnlinked(t *testing.T) {
+	db, store := initAuditChainStore(t, []byte("secret"))
+
+	ctx := context.Background()
+
+	_, err := db.Exec(`INSERT INTO setting_audit (id, sequence, setting_id, tenant_id, setting_key, action,
+		old_value, new_value, actor, reason, request_id, created_at, previous_hash, hash)
+		VALUES ('forged', 0, 's1', '', 'app.name', 'update', 'c', 'x', 'mallory', '', '', '2025-01-01 10:00:00', '', '')`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	chainErr := &AuditChainError{}
+
+	if err := store.VerifyAuditChain(ctx); !errors.As(err, &chainErr) || chainErr.EntryID != "forged" {
+		t.Fatal("Entry outside of the chain MUST break it, found:", err)
+	}
+
+	// the migration on a restart leaves the entry outside of the chain
+	restarted, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AuditTableName:     "setting_audit",
+		AuditHMACKey:       []byte("secret"),
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	if err := restarted.VerifyAuditChain(ctx); !errors.As(err, &chainErr) || chainErr.EntryID != "forged" {
+		t.Fatal("Entry outside of the chain MUST still break it after a restart, found:", err)
+	}
+
+	if _, err := db.Exec(`UPDATE setting_audit SET sequence = NULL WHERE id = 'forged'`); err == nil {
+		t.Fatal("Sequence MUST NOT be null")
+	}
+
+	if _, err := db.Exec(`UPDATE setting_audit SET hash = NULL WHERE id = 'forged'`); err == nil {
+		t.Fatal("Hash MUST NOT be null")
+	}
+
+	if _, err := db.Exec(`UPDATE setting_audit SET sequence = 1 WHERE id = 'forged'`); err == nil {
+		t.Fatal("Sequence MUST be unique")
+	}
+}
+
+func TestStoreAuditChainConcurrentWriters(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	hook := &sqlHook{prefix: `INSERT INTO "setting_audit"`}
+
+	opts := NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AuditTableName:     "setting_audit",
+		AutomigrateEnabled: true,
+	}
+
+	otherStore, err := NewStore(opts)
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	opts.DebugEnabled = true
+	opts.SqlLogger = slog.New(hook)
+
+	store, err := NewStore(opts)
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "app.name", "a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the other writer chains its entry to the same last entry first
+	var errOther error
+
+	hook.fn = func() {
+		errOther = otherStore.WithTx(tx).Set(ctx, "app.theme", "dark")
+	}
+
+	if err := store.WithTx(tx).Set(ctx, "app.name", "b"); err != nil {
+		t.Fatal("Entry MUST be chained again when its sequence is taken, found:", err)
+	}
+
+	if errOther != nil {
+		t.Fatal("unexpected error:", errOther)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.VerifyAuditChain(ctx); err != nil {
+		t.Fatal("Chain MUST NOT fork, found:", err)
+	}
+
+	entries, err := store.SettingAudit(ctx, nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 3 || entries[0].GetNewValue() != "b" || entries[1].GetNewValue() != "dark" {
+		t.Fatal("Entry MUST be chained after the one of the other writer, found:", entries)
+	}
+}
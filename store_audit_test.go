@@ -0,0 +1,196 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dromara/carbon/v2"
+)
+
+func initAuditStore(t *testing.T) StoreInterface {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AuditTableName:     "setting_audit",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	return store
+}
+
+func TestAuditMetadataFromContext(t *testing.T) {
+	ctx := WithActor(context.Background(), "alice")
+	ctx = WithReason(ctx, "TICKET-1")
+	ctx = WithRequestID(ctx, "req-1")
+
+	metadata := AuditMetadataFromContext(ctx)
+
+	if metadata.Actor != "alice" || metadata.Reason != "TICKET-1" || metadata.RequestID != "req-1" {
+		t.Fatal("unexpected metadata:", metadata)
+	}
+
+	if AuditMetadataFromContext(context.Background()) != (AuditMetadata{}) {
+		t.Fatal("Metadata MUST be empty for a context without it")
+	}
+}
+
+func TestStoreSettingAudit(t *testing.T) {
+	store := initAuditStore(t)
+
+	alice := WithRequestID(WithReason(WithActor(context.Background(), "alice"), "TICKET-1"), "req-1")
+	bob := WithActor(context.Background(), "bob")
+
+	if err := store.Set(alice, "mail.host", "smtp.a.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(alice, "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(bob, "mail.host", "smtp.b.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	setting, err := store.SettingFindByKey(bob, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SettingSoftDelete(bob, setting); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SettingDeleteByKey(bob, "mail.host"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	entries, err := store.SettingAudit(context.Background(), SettingAuditQuery().SetSortOrder("asc"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := []struct {
+		actor  string
+		action string
+		key    string
+	}{
+		{"alice", HISTORY_ACTION_CREATE, "mail.host"},
+		{"alice", HISTORY_ACTION_CREATE, "app.name"},
+		{"bob", HISTORY_ACTION_UPDATE, "mail.host"},
+		{"bob", HISTORY_ACTION_SOFT_DELETE, "app.name"},
+		{"bob", HISTORY_ACTION_DELETE, "mail.host"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatal("Audit MUST have 5 entries, found:", len(entries))
+	}
+
+	for i, entry := range entries {
+		if entry.GetActor() != expected[i].actor || entry.GetAction() != expected[i].action || entry.GetKey() != expected[i].key {
+			t.Fatalf("unexpected entry %d: %v", i, entry.Data())
+		}
+	}
+
+	if entries[0].GetReason() != "TICKET-1" || entries[0].GetRequestID() != "req-1" {
+		t.Fatal("Reason and request ID MUST be recorded, found:", entries[0].Data())
+	}
+
+	if entries[2].GetOldValue() != "smtp.a.com" || entries[2].GetNewValue() != "smtp.b.com" {
+		t.Fatal("Old and new values MUST be recorded, found:", entries[2].Data())
+	}
+
+	entries, err = store.SettingAudit(context.Background(), SettingAuditQuery().
+		SetActor("bob").
+		SetKeyPrefix("mail."))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetAction() != HISTORY_ACTION_DELETE {
+		t.Fatal("Audit MUST be filtered by actor and key prefix, newest first, found:", len(entries))
+	}
+
+	entries, err = store.SettingAudit(context.Background(), SettingAuditQuery().SetRequestID("req-1"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatal("Audit MUST be filtered by request ID, found:", len(entries))
+	}
+
+	entries, err = store.SettingAudit(context.Background(), SettingAuditQuery().
+		SetCreatedAtGte(carbon.Now(carbon.UTC).AddHour().ToDateTimeString(carbon.UTC)))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatal("Audit MUST be filtered by time, found:", len(entries))
+	}
+}
+
+func TestStoreSettingAuditScoped(t *testing.T) {
+	store := initAuditStore(t)
+
+	ctx := WithActor(context.Background(), "alice")
+
+	billing := store.ForTenant("acme").Namespace("billing")
+
+	if err := billing.Set(ctx, "currency", "EUR"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "currency", "USD"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := billing.DeleteByPrefix(ctx, "cur"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	entries, err := billing.SettingAudit(ctx, nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatal("Audit MUST only show the changes of the tenant, found:", len(entries))
+	}
+
+	if entries[0].GetKey() != "currency" || entries[0].GetTenantID() != "acme" || entries[0].GetActor() != "alice" {
+		t.Fatal("unexpected entry:", entries[0].Data())
+	}
+}
+
+func TestStoreSettingAuditNotEnabled(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	if _, err := store.SettingAudit(context.Background(), nil); !errors.Is(err, errAuditNotEnabled) {
+		t.Fatal("SettingAudit MUST fail if the audit is not enabled, found:", err)
+	}
+}
@@ -0,0 +1,221 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// bulkReservedParams is the number of parameters of a bulk statement
+// kept for its other conditions, i.e. the tenant and the namespace
+const bulkReservedParams = 20
+
+// maxQueryParams returns the number of parameters a single statement
+// may have on the dialect
+func maxQueryParams(dialect string) int {
+	switch dialect {
+	case sb.DIALECT_SQLITE, "sqlite3":
+		return 999 // the default before SQLite 3.32
+	case sb.DIALECT_MSSQL, "sqlserver":
+		return 2100
+	}
+
+	return 65535 // MySQL and Postgres
+}
+
+// bulkChunkSize returns the number of items a bulk statement may have
+// on the dialect, with the number of parameters each item takes
+func bulkChunkSize(dialect string, paramsPerItem int) int {
+	return max(1, (maxQueryParams(dialect)-bulkReservedParams)/max(1, paramsPerItem))
+}
+
+// GetMany gets the values of the settings by key
+//
+// The settings are loaded with a single IN query, chunked under the
+// parameter limit of the dialect. If the cache is enabled, only the
+// settings which are not cached are loaded
+//
+// Parameters:
+// - ctx: the context
+// - settingKeys: the keys of the settings to get
+//
+// Returns:
+// - map[string]string - the values by key, without the settings not found
+// - error - nil if no error, error otherwise
+func (st *store) GetMany(ctx context.Context, settingKeys []string) (map[string]string, error) {
+	if lo.Contains(settingKeys, "") {
+		return nil, errors.New("settingstore > get many. key cannot be empty")
+	}
+
+	values := map[string]string{}
+	missingKeys := lo.Uniq(settingKeys)
+
+	// a transaction sees its own uncommitted writes, so it bypasses the cache
+	cached := st.cache != nil && st.transaction == nil
+	generations := map[string]uint64{}
+
+	if cached {
+		if err := st.cacheCheckRevision(ctx); err != nil {
+			return nil, err
+		}
+
+		if st.scopeErr != nil {
+			return nil, st.scopeErr
+		}
+
+		missingKeys = lo.Filter(missingKeys, func(key string, _ int) bool {
+			entry, hit, generation := st.cache.get(st.cacheKey(key))
+
+			if !hit {
+				generations[key] = generation
+				return true
+			}
+
+			if entry.found {
+				values[key] = entry.value
+			}
+
+			return false
+		})
+	}
+
+	for _, chunk := range lo.Chunk(missingKeys, bulkChunkSize(st.dbDriverName, 1)) {
+		list, err := st.settingList(ctx, SettingQuery().SetKeyIn(chunk))
+
+		if err != nil {
+			return nil, err
+		}
+
+		settings := lo.KeyBy(list, func(setting SettingInterface) string {
+			return setting.GetKey()
+		})
+
+		for _, key := range chunk {
+			setting, found := settings[key]
+
+			if found {
+				values[key] = setting.GetValue()
+			}
+
+			if cached {
+				st.cache.set(newSettingCacheEntry(st.cacheKey(key), setting), generations[key])
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// SetMany saves the values by key, in a single transaction
+//
+// The settings are saved with multi-row upserts, chunked under the
+// parameter limit of the dialect. The saved settings never expire
+//
+// Parameters:
+// - ctx: the context
+// - values: the values to save, by key
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetMany(ctx context.Context, values map[string]string) error {
+	if _, hasEmpty := values[""]; hasEmpty {
+		return errors.New("settingstore > set many. key cannot be empty")
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	return st.upsert(ctx, values, expiresAtFromSeconds(0))
+}
+
+// DeleteMany hard deletes the settings by key, in a single transaction
+//
+// The settings are deleted with IN statements, chunked under the
+// parameter limit of the dialect
+//
+// Parameters:
+// - ctx: the context
+// - settingKeys: the keys of the settings to delete
+//
+// Returns:
+// - int64 - the number of settings deleted
+// - error - nil if no error, error otherwise
+func (st *store) DeleteMany(ctx context.Context, settingKeys []string) (int64, error) {
+	if lo.Contains(settingKeys, "") {
+		return 0, errors.New("settingstore > delete many. key cannot be empty")
+	}
+
+	settingKeys = lo.Uniq(settingKeys)
+
+	if len(settingKeys) == 0 {
+		return 0, nil
+	}
+
+	deleted := int64(0)
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		for _, chunk := range lo.Chunk(settingKeys, bulkChunkSize(txStore.dbDriverName, 1)) {
+			conditions := []exp.Expression{
+				goqu.C(COLUMN_SETTING_KEY).In(lo.Map(chunk, func(key string, _ int) string {
+					return txStore.namespacedKey(key)
+				})),
+				txStore.tenantExpression(),
+			}
+
+			sqlStr, params, errSql := goqu.Dialect(txStore.dbDriverName).
+				Delete(txStore.settingTableName).
+				Prepared(true).
+				Where(conditions...).
+				ToSQL()
+
+			if errSql != nil {
+				return errSql
+			}
+
+			txStore.logSql("delete", sqlStr, params...)
+
+			rows, err := txStore.loadChangedRows(ctx, conditions...)
+
+			if err != nil {
+				return err
+			}
+
+			result, err := txStore.executeSql(ctx, sqlStr, params...)
+
+			if err != nil {
+				return err
+			}
+
+			affected, err := result.RowsAffected()
+
+			if err != nil {
+				return err
+			}
+
+			deleted += affected
+
+			if err := txStore.recordDeletion(ctx, rows, HISTORY_ACTION_DELETE); err != nil {
+				return err
+			}
+		}
+
+		txStore.afterCommit(func() {
+			for _, key := range settingKeys {
+				txStore.cacheInvalidateKey(key)
+			}
+		})
+
+		return txStore.revisionBump(ctx)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
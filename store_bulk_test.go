@@ -0,0 +1,151 @@
+package settingstore
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestStoreGetMany(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+		CacheEnabled:       true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	billing := store.Namespace("billing")
+
+	for key, value := range map[string]string{"currency": "EUR", "vat": "20"} {
+		if err := billing.Set(ctx, key, value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		values, err := billing.GetMany(ctx, []string{"currency", "vat", "vat", "missing"})
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if len(values) != 2 || values["currency"] != "EUR" || values["vat"] != "20" {
+			t.Fatal("Values of the existing settings MUST be returned, found:", values)
+		}
+	}
+
+	stats := store.CacheStats()
+
+	// the missing key is not cached, unless enabled
+	if stats.Misses != 4 || stats.Hits != 2 {
+		t.Fatal("Second call MUST be served from the cache, found:", stats)
+	}
+
+	if _, err := store.GetMany(ctx, []string{""}); err == nil {
+		t.Fatal("Empty key MUST be rejected")
+	}
+}
+
+func TestStoreSetMany(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	// more settings than fit in a single statement on SQLite
+	values := map[string]string{}
+	keys := []string{}
+
+	for i := 0; i < 500; i++ {
+		key := "seed." + strconv.Itoa(i)
+		values[key] = strconv.Itoa(i)
+		keys = append(keys, key)
+	}
+
+	if err := store.SetMany(ctx, values); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	values["seed.0"] = "updated"
+
+	if err := store.SetMany(ctx, values); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	count, err := store.SettingCount(ctx, SettingQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 500 {
+		t.Fatal("Each key MUST be saved once, found:", count)
+	}
+
+	found, err := store.GetMany(ctx, keys)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(found) != 500 || found["seed.0"] != "updated" || found["seed.499"] != "499" {
+		t.Fatal("Saved values MUST be returned, found:", len(found), found["seed.0"], found["seed.499"])
+	}
+
+	if err := store.SetMany(ctx, map[string]string{"": "empty"}); err == nil {
+		t.Fatal("Empty key MUST be rejected")
+	}
+}
+
+func TestStoreDeleteMany(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	err = store.SetMany(ctx, map[string]string{"a": "1", "b": "2", "c": "3"})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	deleted, err := store.DeleteMany(ctx, []string{"a", "b", "missing"})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if deleted != 2 {
+		t.Fatal("Deleted settings MUST be counted, found:", deleted)
+	}
+
+	values, err := store.GetMany(ctx, []string{"a", "b", "c"})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(values) != 1 || values["c"] != "3" {
+		t.Fatal("Only the other settings MUST remain, found:", values)
+	}
+}
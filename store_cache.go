@@ -0,0 +1,127 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// CacheStats returns the hit and miss counters of the setting cache
+//
+// # If the cache is not enabled, all the counters are zero
+//
+// Returns:
+// - CacheStats - the cache counters
+func (store *store) CacheStats() CacheStats {
+	if store.cache == nil {
+		return CacheStats{}
+	}
+
+	return store.cache.stats()
+}
+
+// findValueByKey finds the value of a setting by key, through the cache if enabled
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to find
+//
+// Returns:
+// - string - the value of the setting
+// - bool - true if the setting was found, false otherwise
+// - error - nil if no error, error otherwise
+func (store *store) findValueByKey(ctx context.Context, settingKey string) (string, bool, error) {
+	if settingKey == "" {
+		return "", false, errors.New("setting store > find by key: setting key is required")
+	}
+
+	// a transaction sees its own uncommitted writes, so it bypasses the cache
+	if store.cache == nil || store.transaction != nil {
+		setting, err := store.settingFindByKey(ctx, settingKey)
+
+		if err != nil || setting == nil {
+			return "", false, err
+		}
+
+		return setting.GetValue(), true, nil
+	}
+
+	if err := store.cacheCheckRevision(ctx); err != nil {
+		return "", false, err
+	}
+
+	if store.scopeErr != nil {
+		return "", false, store.scopeErr
+	}
+
+	cacheKey := store.cacheKey(settingKey)
+
+	cached, hit, generation := store.cache.get(cacheKey)
+
+	if hit {
+		return cached.value, cached.found, nil
+	}
+
+	setting, err := store.settingFindByKey(ctx, settingKey)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	entry := newSettingCacheEntry(cacheKey, setting)
+
+	store.cache.set(entry, generation)
+
+	return entry.value, entry.found, nil
+}
+
+// cacheInvalidateKey removes the setting with the key from the cache, if enabled
+func (store *store) cacheInvalidateKey(settingKey string) {
+	if store.cache != nil {
+		store.cache.invalidateKey(store.cacheKey(settingKey))
+	}
+}
+
+// cacheInvalidateID removes the setting with the ID from the cache, if enabled
+func (store *store) cacheInvalidateID(settingID string) {
+	if store.cache != nil {
+		store.cache.invalidateID(settingID)
+	}
+}
+
+// cacheKey returns the key of the setting in the cache, which is shared
+// by the namespaced and the tenant scoped views of the store
+func (store *store) cacheKey(settingKey string) string {
+	return store.tenantID + "\x00" + store.namespacedKey(settingKey)
+}
+
+// newSettingCacheEntry returns the cache entry of the setting,
+// an entry of a missing setting if nil
+func newSettingCacheEntry(cacheKey string, setting SettingInterface) settingCacheEntry {
+	entry := settingCacheEntry{key: cacheKey}
+
+	if setting != nil {
+		entry.settingID = setting.GetID()
+		entry.value = setting.GetValue()
+		entry.found = true
+		entry.expiresAt = settingExpiresAtTime(setting)
+	}
+
+	return entry
+}
+
+// settingExpiresAtTime returns the expiry time of the setting,
+// or the zero time if the setting does not expire
+func settingExpiresAtTime(setting SettingInterface) time.Time {
+	if setting.GetExpiresAt() == "" {
+		return time.Time{}
+	}
+
+	expiresAt := setting.GetExpiresAtCarbon()
+
+	if expiresAt.HasError() || expiresAt.IsZero() {
+		return time.Time{}
+	}
+
+	return expiresAt.StdTime()
+}
@@ -0,0 +1,137 @@
+package settingstore
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/samber/lo"
+)
+
+// recordedChange is a change of a setting, to be recorded
+// in the history and the audit tables
+type recordedChange struct {
+	settingID string
+	tenantID  string
+	key       string // the full key, with the namespace
+	action    string
+	oldValue  string
+	newValue  string
+	createdAt string // optional, defaults to now
+}
+
+// changesRecorded returns true if the changes of the settings are
+// recorded, in the history or the audit table
+func (store *store) changesRecorded() bool {
+	return store.historyTableName != "" || store.auditTableName != ""
+}
+
+// recordChanges records the changes in the history and the audit tables,
+// in the transaction the store is bound to
+func (store *store) recordChanges(ctx context.Context, changes ...recordedChange) error {
+	if err := store.historyRecord(ctx, changes...); err != nil {
+		return err
+	}
+
+	return store.auditRecord(ctx, changes...)
+}
+
+// loadChangedRows loads the rows of the settings table matching the
+// conditions, before they are changed. It loads nothing if the changes
+// are not recorded
+func (store *store) loadChangedRows(ctx context.Context, conditions ...exp.Expression) ([]map[string]string, error) {
+	if !store.changesRecorded() {
+		return []map[string]string{}, nil
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.settingTableName).
+		Prepared(true).
+		Select(COLUMN_ID, COLUMN_TENANT_ID, COLUMN_SETTING_KEY, COLUMN_SETTING_VALUE, COLUMN_UPDATED_AT, COLUMN_SOFT_DELETED_AT).
+		Where(conditions...).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	return store.selectToMapString(ctx, sqlStr, params...)
+}
+
+// recordDeletion records the deletion of the rows, loaded before
+// they were deleted or soft deleted. Rows soft deleted already were
+// recorded as deleted then, and are skipped
+func (store *store) recordDeletion(ctx context.Context, rows []map[string]string, action string) error {
+	rows = lo.Reject(rows, func(row map[string]string, _ int) bool {
+		return isPastDateTime(row[COLUMN_SOFT_DELETED_AT])
+	})
+
+	return store.recordChanges(ctx, lo.Map(rows, func(row map[string]string, _ int) recordedChange {
+		return recordedChange{
+			settingID: row[COLUMN_ID],
+			tenantID:  row[COLUMN_TENANT_ID],
+			key:       row[COLUMN_SETTING_KEY],
+			action:    action,
+			oldValue:  row[COLUMN_SETTING_VALUE],
+		}
+	})...)
+}
+
+// updateChange returns the change made by an update of the setting,
+// nil if the changes are not recorded or neither the value nor the
+// deletion of the setting changes
+func (store *store) updateChange(ctx context.Context, setting SettingInterface, dataChanged map[string]string) (*recordedChange, error) {
+	if !store.changesRecorded() {
+		return nil, nil
+	}
+
+	rows, err := store.loadChangedRows(ctx,
+		goqu.C(COLUMN_ID).Eq(setting.GetID()),
+		store.tenantExpression(),
+		store.namespaceExpression())
+
+	if err != nil || len(rows) < 1 {
+		return nil, err
+	}
+
+	row := rows[0]
+
+	oldValue := row[COLUMN_SETTING_VALUE]
+	newValue, valueChanged := dataChanged[COLUMN_SETTING_VALUE]
+
+	if !valueChanged {
+		newValue = oldValue
+	}
+
+	wasDeleted := isPastDateTime(row[COLUMN_SOFT_DELETED_AT])
+	isDeleted := wasDeleted
+
+	if softDeletedAt, changed := dataChanged[COLUMN_SOFT_DELETED_AT]; changed {
+		isDeleted = isPastDateTime(softDeletedAt)
+	}
+
+	change := &recordedChange{
+		settingID: setting.GetID(),
+		tenantID:  store.tenantID,
+		key:       store.namespacedKey(setting.GetKey()),
+		action:    HISTORY_ACTION_UPDATE,
+		oldValue:  oldValue,
+		newValue:  newValue,
+	}
+
+	switch {
+	case isDeleted && !wasDeleted:
+		change.action = HISTORY_ACTION_SOFT_DELETE
+		change.newValue = ""
+	case isDeleted:
+		return nil, nil // changes of a soft deleted setting are not visible
+	case wasDeleted:
+		change.oldValue = "" // restored
+	case store.sameValue(store.tenantID, change.key, oldValue, newValue):
+		return nil, nil
+	}
+
+	return change, nil
+}
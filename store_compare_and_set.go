@@ -0,0 +1,62 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+)
+
+// CompareAndSet atomically replaces the value of a setting, only if it
+// still has the expected value
+//
+// The setting is updated with its version checked, so a concurrent change
+// between the comparison and the update is never overwritten
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - expectedValue: the value the setting must have
+// - newValue: the value to save
+//
+// Returns:
+// - bool - true if the value was replaced, false if the setting does not
+// exist or does not have the expected value
+// - error - nil if no error, error otherwise
+func (st *store) CompareAndSet(ctx context.Context, settingKey string, expectedValue string, newValue string) (bool, error) {
+	if settingKey == "" {
+		return false, errors.New("settingstore > compare and set. key cannot be empty")
+	}
+
+	swapped := false
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		setting, err := txStore.settingFindByKey(ctx, settingKey)
+
+		if err != nil {
+			return err
+		}
+
+		if setting == nil || setting.GetValue() != expectedValue {
+			return nil
+		}
+
+		setting.SetValue(newValue)
+
+		if err := txStore.SettingUpdate(ctx, setting); err != nil {
+			if errors.Is(err, ErrConflict) {
+				return nil // changed in the meantime, so no longer the expected value
+			}
+
+			return err
+		}
+
+		swapped = true
+
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return swapped, nil
+}
@@ -0,0 +1,115 @@
+package settingstore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStoreCompareAndSet(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	swapped, err := store.CompareAndSet(ctx, "feature.mode", "", "on")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if swapped {
+		t.Fatal("CompareAndSet MUST NOT set a missing setting")
+	}
+
+	if err := store.Set(ctx, "feature.mode", "off"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	swapped, err = store.CompareAndSet(ctx, "feature.mode", "on", "beta")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if swapped {
+		t.Fatal("CompareAndSet MUST NOT replace an unexpected value")
+	}
+
+	swapped, err = store.CompareAndSet(ctx, "feature.mode", "off", "on")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !swapped {
+		t.Fatal("CompareAndSet MUST replace the expected value")
+	}
+
+	value, err := store.Get(ctx, "feature.mode", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "on" {
+		t.Fatal("Value MUST be 'on', found:", value)
+	}
+}
+
+func TestStoreCompareAndSetConcurrent(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "lock.owner", ""); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	winners := atomic.Int64{}
+	wg := sync.WaitGroup{}
+
+	for _, owner := range []string{"a", "b", "c", "d", "e"} {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			swapped, err := store.CompareAndSet(ctx, "lock.owner", "", owner)
+
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+
+			if swapped {
+				winners.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if winners.Load() != 1 {
+		t.Fatal("Exactly one CompareAndSet MUST win, found:", winners.Load())
+	}
+}
@@ -0,0 +1,231 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/sb"
+)
+
+// Increment atomically adds the delta to the integer value of a setting
+//
+// The value is changed with a single UPDATE, so concurrent increments,
+// from any number of processes, are never lost. A missing setting is
+// created with the delta as its value, and an expired one starts over
+// from the delta, and no longer expires
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - delta: the number to add, negative to subtract
+//
+// Returns:
+// - int64 - the new value of the setting
+// - error - a *ParseError if the value is not an integer, nil if no error, error otherwise
+func (st *store) Increment(ctx context.Context, settingKey string, delta int64) (int64, error) {
+	if settingKey == "" {
+		return 0, errors.New("settingstore > increment. key cannot be empty")
+	}
+
+	if st.isSecretKey(st.namespacedKey(settingKey)) {
+		return 0, errors.New("settingstore > increment. secret settings cannot be incremented")
+	}
+
+	value := int64(0)
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		for attempt := 0; attempt < conditionalWriteAttempts; attempt++ {
+			incremented, updated, err := txStore.incrementLive(ctx, settingKey, delta)
+
+			if err != nil || updated {
+				value = incremented
+				return err
+			}
+
+			// not updated: the value is not an integer, or the setting is expired or missing
+			list, err := txStore.settingList(ctx, SettingQuery().
+				SetKey(settingKey).
+				SetExpiredIncluded(true).
+				SetLimit(1))
+
+			if err != nil {
+				return err
+			}
+
+			if len(list) > 0 && !isPastDateTime(list[0].GetExpiresAt()) {
+				_, errParse := strconv.ParseInt(list[0].GetValue(), 10, 64)
+
+				if errParse == nil {
+					errParse = strconv.ErrSyntax // accepted by Go, but not by the database, i.e. a leading +
+				}
+
+				return newParseError(settingKey, "int64", list[0].GetValue(), errParse, txStore.isSensitiveSettingKey(settingKey))
+			}
+
+			done := false
+
+			if len(list) > 0 {
+				done, err = txStore.replaceExpired(ctx, list[0], strconv.FormatInt(delta, 10))
+			} else {
+				done, err = txStore.insertIfAbsent(ctx, NewSetting().
+					SetKey(settingKey).
+					SetValue(strconv.FormatInt(delta, 10)))
+			}
+
+			if err != nil || done {
+				value = delta
+				return err
+			}
+
+			// created or restarted concurrently, so incremented on the next attempt
+		}
+
+		return ErrConflict
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// Decrement atomically subtracts the delta from the integer value of a
+// setting, it is the same as Increment with the negated delta
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - delta: the number to subtract, negative to add, math.MinInt64 is not allowed
+//
+// Returns:
+// - int64 - the new value of the setting
+// - error - a *ParseError if the value is not an integer, nil if no error, error otherwise
+func (st *store) Decrement(ctx context.Context, settingKey string, delta int64) (int64, error) {
+	// the negated math.MinInt64 overflows back to itself
+	if delta == math.MinInt64 {
+		return 0, errors.New("settingstore > decrement. delta cannot be negated")
+	}
+
+	return st.Increment(ctx, settingKey, -delta)
+}
+
+// incrementLive adds the delta to the value of the live setting,
+// if it is an integer and not expired
+//
+// Returns:
+// - int64 - the new value of the setting
+// - bool - true if the setting was updated, false otherwise
+// - error - nil if no error, error otherwise
+func (store *store) incrementLive(ctx context.Context, settingKey string, delta int64) (int64, bool, error) {
+	now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+	integerType, textType, isInteger := incrementSqls(store.dbDriverName)
+
+	conditions := []exp.Expression{
+		goqu.C(COLUMN_SETTING_KEY).Eq(store.namespacedKey(settingKey)),
+		store.tenantExpression(),
+		goqu.C(COLUMN_SOFT_DELETED_AT).Gt(now),
+		goqu.Or(
+			goqu.C(COLUMN_EXPIRES_AT).IsNull(),
+			goqu.C(COLUMN_EXPIRES_AT).Gt(now),
+		),
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(store.settingTableName).
+		Prepared(true).
+		Set(goqu.Record{
+			COLUMN_SETTING_VALUE: goqu.L("CAST(CAST(? AS "+integerType+") + ? AS "+textType+")", goqu.C(COLUMN_SETTING_VALUE), delta),
+			COLUMN_UPDATED_AT:    now,
+			COLUMN_VERSION:       versionIncrementExpression(),
+		}).
+		Where(conditions...).
+		Where(isInteger).
+		ToSQL()
+
+	if errSql != nil {
+		return 0, false, errSql
+	}
+
+	store.logSql("update", sqlStr, params...)
+
+	rows, err := store.loadChangedRows(ctx, conditions...)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	result, err := store.executeSql(ctx, sqlStr, params...)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil || affected < 1 {
+		return 0, false, err
+	}
+
+	// read in the transaction, which holds the lock of the updated row
+	setting, err := store.settingFindByKey(ctx, settingKey)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if setting == nil {
+		return 0, false, errors.New("settingstore > increment. setting not found after the update")
+	}
+
+	value, err := strconv.ParseInt(setting.GetValue(), 10, 64)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if len(rows) > 0 {
+		errRecord := store.recordChanges(ctx, recordedChange{
+			settingID: rows[0][COLUMN_ID],
+			tenantID:  store.tenantID,
+			key:       rows[0][COLUMN_SETTING_KEY],
+			action:    HISTORY_ACTION_UPDATE,
+			oldValue:  rows[0][COLUMN_SETTING_VALUE],
+			newValue:  setting.GetValue(),
+		})
+
+		if errRecord != nil {
+			return 0, false, errRecord
+		}
+	}
+
+	store.afterCommit(func() {
+		store.cacheInvalidateKey(settingKey)
+	})
+
+	return value, true, store.revisionBump(ctx)
+}
+
+// incrementSqls returns the integer and the text types of the dialect,
+// and the condition matching the settings with an integer value
+func incrementSqls(dialect string) (integerType string, textType string, isInteger exp.Expression) {
+	value := goqu.C(COLUMN_SETTING_VALUE)
+
+	switch dialect {
+	case sb.DIALECT_MYSQL:
+		return "SIGNED", "CHAR", goqu.L("? REGEXP ?", value, "^-?[0-9]+$")
+	case sb.DIALECT_POSTGRES:
+		return "BIGINT", "TEXT", goqu.L("? ~ ?", value, "^-?[0-9]+$")
+	case sb.DIALECT_MSSQL, "sqlserver":
+		return "BIGINT", "NVARCHAR(MAX)", goqu.L("TRY_CAST(? AS BIGINT) IS NOT NULL", value)
+	}
+
+	// SQLite has no regular expressions, the optional minus is followed by digits only
+	return "INTEGER", "TEXT", goqu.L("(? GLOB '[0-9]*' OR ? GLOB '-[0-9]*') AND SUBSTR(?, 2) NOT GLOB '*[^0-9]*'", value, value, value)
+}
@@ -0,0 +1,202 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestStoreIncrement(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	steps := []struct {
+		delta    int64
+		expected int64
+	}{
+		{5, 5}, // created on first use
+		{2, 7},
+		{-10, -3},
+	}
+
+	for _, step := range steps {
+		value, err := store.Increment(ctx, "invoice.sequence", step.delta)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if value != step.expected {
+			t.Fatalf("Value MUST be %d, found: %d", step.expected, value)
+		}
+	}
+
+	value, err := store.Decrement(ctx, "invoice.sequence", 1)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != -4 {
+		t.Fatal("Decrement MUST subtract the delta, found:", value)
+	}
+
+	stored, err := store.Get(ctx, "invoice.sequence", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if stored != "-4" {
+		t.Fatal("Value MUST be saved, found:", stored)
+	}
+
+	if _, err := store.Decrement(ctx, "invoice.sequence", math.MinInt64); err == nil {
+		t.Fatal("Decrement MUST reject the delta which cannot be negated")
+	}
+
+	if stored, _ := store.Get(ctx, "invoice.sequence", ""); stored != "-4" {
+		t.Fatal("Value MUST NOT be changed by the rejected delta, found:", stored)
+	}
+}
+
+func TestStoreIncrementNotNumeric(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	for _, value := range []string{"abc", "1.5", "+5", ""} {
+		if err := store.Set(ctx, "quota", value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		_, err := store.Increment(ctx, "quota", 1)
+
+		parseErr := &ParseError{}
+
+		if !errors.As(err, &parseErr) || parseErr.Key != "quota" || parseErr.Value != value {
+			t.Fatalf("Value %q MUST be rejected with a ParseError, found: %v", value, err)
+		}
+
+		stored, err := store.Get(ctx, "quota", "")
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if stored != value {
+			t.Fatal("Rejected value MUST NOT be changed, found:", stored)
+		}
+	}
+}
+
+func TestStoreIncrementExpired(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.SetWithTTL(ctx, "quota.daily", "100", 60); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := db.Exec(`UPDATE setting SET expires_at = '2020-01-01 00:00:00'`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.Increment(ctx, "quota.daily", 3)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != 3 {
+		t.Fatal("Expired counter MUST start over, found:", value)
+	}
+
+	count, err := store.SettingCount(ctx, SettingQuery().SetExpiredIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("Expired row MUST be reused, found rows:", count)
+	}
+}
+
+func TestStoreIncrementConcurrent(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				if _, err := store.Increment(ctx, "counter", 1); err != nil {
+					t.Error("unexpected error:", err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	value, err := store.GetInt64(ctx, "counter", 0)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != 100 {
+		t.Fatal("No increment MUST be lost, found:", value)
+	}
+}
@@ -0,0 +1,258 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/samber/lo"
+)
+
+var errEncryptionNotEnabled = errors.New("settingstore: encryption is not enabled, set EncryptionKeys")
+
+// errReencryptScoped is returned by ReencryptAll called on a tenant scoped
+// or a namespaced view, as it covers all the tenants and the namespaces
+var errReencryptScoped = errors.New("settingstore: ReencryptAll cannot be called on a tenant scoped or a namespaced view")
+
+// reencryptBatchSize is the number of rows ReencryptAll loads at a time
+const reencryptBatchSize = 100
+
+// ReencryptAll makes the key the one sealing the new values, and seals
+// again with it the values of the secret settings sealed with another key
+//
+// The values are resealed one row at a time, each only if it was not
+// changed in the meantime, so the settings stay readable and writable
+// during the rotation. The plaintext values of the keys which became
+// secret are sealed too. It covers all the tenants and the namespaces,
+// and the history of the changes, so it cannot be called on a tenant
+// scoped or a namespaced view. The audit trail keeps the values it
+// recorded, as rewriting them would break its hash chain, so the retired
+// keys must stay in the keyring as long as those entries must be read
+//
+// To rotate the keys, add the new key to EncryptionKeys, and make it the
+// EncryptionKeyID, in every process, keeping the old key in the keyring.
+// Then call ReencryptAll with the new key. It can be called again, to
+// reseal the values saved meanwhile by the processes not restarted yet
+//
+// Parameters:
+// - ctx: the context
+// - newKeyID: the ID of the key in the keyring to seal with
+//
+// Returns:
+// - int64 - the number of rows resealed
+// - error - nil if no error, error otherwise
+func (st *store) ReencryptAll(ctx context.Context, newKeyID string) (int64, error) {
+	if st.scopeErr != nil {
+		return 0, st.scopeErr
+	}
+
+	if st.tenantScoped || st.keyPrefix != "" {
+		return 0, errReencryptScoped
+	}
+
+	if st.encryption == nil {
+		return 0, errEncryptionNotEnabled
+	}
+
+	if err := st.encryption.setActiveKeyID(newKeyID); err != nil {
+		return 0, err
+	}
+
+	if st.encryption.secrets.isEmpty() {
+		return 0, nil // no secret settings to reseal
+	}
+
+	resealed, err := st.reencryptTable(ctx, st.settingTableName, COLUMN_SETTING_VALUE)
+
+	if err != nil || st.historyTableName == "" {
+		return resealed, err
+	}
+
+	resealedHistory, err := st.reencryptTable(ctx, st.historyTableName, COLUMN_OLD_VALUE, COLUMN_NEW_VALUE)
+
+	return resealed + resealedHistory, err
+}
+
+// reencryptTable reseals the value columns of the rows of the secret
+// settings in the table with the active key, in batches ordered by ID.
+// Each row is updated only if its values are still the ones loaded
+func (store *store) reencryptTable(ctx context.Context, tableName string, valueColumns ...string) (int64, error) {
+	columns := append([]any{COLUMN_ID, COLUMN_TENANT_ID, COLUMN_SETTING_KEY}, lo.ToAnySlice(valueColumns)...)
+
+	resealed := int64(0)
+	lastID := ""
+
+	for {
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			From(tableName).
+			Prepared(true).
+			Select(columns...).
+			Where(store.secretKeysExpression()).
+			Where(goqu.C(COLUMN_ID).Gt(lastID)).
+			Order(goqu.C(COLUMN_ID).Asc()).
+			Limit(reencryptBatchSize).
+			ToSQL()
+
+		if errSql != nil {
+			return resealed, errSql
+		}
+
+		store.logSql("select", sqlStr, params...)
+
+		rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+		if err != nil {
+			return resealed, err
+		}
+
+		for _, row := range rows {
+			lastID = row[COLUMN_ID]
+
+			updated, err := store.reencryptRow(ctx, tableName, row, valueColumns)
+
+			if err != nil {
+				return resealed, err
+			}
+
+			if updated {
+				resealed++
+			}
+		}
+
+		if len(rows) < reencryptBatchSize {
+			return resealed, nil
+		}
+	}
+}
+
+// reencryptRow reseals the values of the row which were not sealed
+// with the active key, unless they were changed since they were loaded
+func (store *store) reencryptRow(ctx context.Context, tableName string, row map[string]string, valueColumns []string) (bool, error) {
+	if !store.isSecretKey(row[COLUMN_SETTING_KEY]) {
+		return false, nil // matched by LIKE only, i.e. case insensitively
+	}
+
+	record := goqu.Record{}
+	conditions := []exp.Expression{goqu.C(COLUMN_ID).Eq(row[COLUMN_ID])}
+
+	for _, column := range valueColumns {
+		value := row[column]
+
+		if keyID, _, sealed := store.encryption.parse(value); value == "" || (sealed && keyID == store.encryption.activeKey()) {
+			continue
+		}
+
+		opened, err := store.encryption.open(row[COLUMN_TENANT_ID], row[COLUMN_SETTING_KEY], value)
+
+		if err != nil {
+			return false, err
+		}
+
+		resealed, err := store.encryption.seal(row[COLUMN_TENANT_ID], row[COLUMN_SETTING_KEY], opened)
+
+		if err != nil {
+			return false, err
+		}
+
+		record[column] = resealed
+		conditions = append(conditions, goqu.C(column).Eq(value))
+	}
+
+	if len(record) == 0 {
+		return false, nil
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(tableName).
+		Prepared(true).
+		Set(record).
+		Where(conditions...).
+		ToSQL()
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	logParams := params
+
+	for column, value := range record {
+		logParams = store.redactParams(logParams, row[COLUMN_SETTING_KEY], row[column], value.(string))
+	}
+
+	store.logSql("update", sqlStr, logParams...)
+
+	result, err := store.executeSql(ctx, sqlStr, params...)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// secretKeysExpression returns the condition matching the full keys
+// of the secret settings, in all the tenants and the namespaces
+func (store *store) secretKeysExpression() exp.Expression {
+	conditions := []exp.Expression{}
+
+	if len(store.encryption.secrets.keys) > 0 {
+		conditions = append(conditions, goqu.C(COLUMN_SETTING_KEY).In(lo.Keys(store.encryption.secrets.keys)))
+	}
+
+	for _, prefix := range store.encryption.secrets.prefixes {
+		conditions = append(conditions, keyPrefixExpression(prefix))
+	}
+
+	return goqu.Or(conditions...)
+}
+
+// isSecretKey returns true if the value of the full key, with its
+// namespace, is encrypted at rest
+func (store *store) isSecretKey(fullKey string) bool {
+	return store.encryption != nil && store.encryption.secrets.matches(fullKey)
+}
+
+// sealValue returns the value to save for the full key, sealed with the
+// active key if the key is secret. Empty values are saved as they are
+func (store *store) sealValue(tenantID string, fullKey string, value string) (string, error) {
+	if value == "" || !store.isSecretKey(fullKey) {
+		return value, nil
+	}
+
+	return store.encryption.seal(tenantID, fullKey, value)
+}
+
+// openValue returns the value saved for the full key, opened if the key
+// is secret
+func (store *store) openValue(tenantID string, fullKey string, value string) (string, error) {
+	if !store.isSecretKey(fullKey) {
+		return value, nil
+	}
+
+	return store.encryption.open(tenantID, fullKey, value)
+}
+
+// sameValue returns true if the values saved for the full key are the
+// same once opened, as a value sealed twice has two ciphertexts
+func (store *store) sameValue(tenantID string, fullKey string, value string, other string) bool {
+	if value == other {
+		return true
+	}
+
+	opened, err := store.openValue(tenantID, fullKey, value)
+
+	if err != nil {
+		return false
+	}
+
+	openedOther, err := store.openValue(tenantID, fullKey, other)
+
+	return err == nil && opened == openedOther
+}
@@ -0,0 +1,284 @@
+package settingstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func initEncryptedStore(t *testing.T, db *sql.DB, keys map[string][]byte, keyID string) StoreInterface {
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		HistoryTableName:   "setting_history",
+		AutomigrateEnabled: true,
+		EncryptionKeys:     keys,
+		EncryptionKeyID:    keyID,
+		SecretKeys:         []string{"api.token"},
+		SecretKeyPrefixes:  []string{"smtp."},
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	return store
+}
+
+func rawSettingValue(t *testing.T, db *sql.DB, settingKey string) string {
+	value := ""
+
+	if err := db.QueryRow(`SELECT setting_value FROM setting WHERE setting_key = ?`, settingKey).Scan(&value); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	return value
+}
+
+func unmaskedHistory(st StoreInterface, ctx context.Context, settingKey string) ([]SettingHistoryEntryInterface, error) {
+	return st.(*store).settingHistory(ctx, settingKey, nil)
+}
+
+func activeEncryptionKeyID(st StoreInterface) string {
+	return st.(*store).encryption.activeKey()
+}
+
+func TestStoreEncryption(t *testing.T) {
+	db, _ := initSharedMemoryStore(t)
+
+	store := initEncryptedStore(t, db, map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)}, "k1")
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "smtp.password", "hunter2"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Namespace("smtp").Set(ctx, "user", "mailer"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetMany(ctx, map[string]string{"api.token": "t0ken", "site.name": "Acme"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for key, value := range map[string]string{"smtp.password": "hunter2", "smtp.user": "mailer", "api.token": "t0ken"} {
+		raw := rawSettingValue(t, db, key)
+
+		if !strings.HasPrefix(raw, "enc:v1:k1:") || strings.Contains(raw, value) {
+			t.Fatalf("Secret %q MUST be saved encrypted, found: %q", key, raw)
+		}
+
+		stored, err := store.Get(ctx, key, "")
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if stored != value {
+			t.Fatalf("Secret %q MUST be decrypted on read, found: %q", key, stored)
+		}
+	}
+
+	if raw := rawSettingValue(t, db, "site.name"); raw != "Acme" {
+		t.Fatal("Other settings MUST NOT be encrypted, found:", raw)
+	}
+
+	if err := store.Set(ctx, "smtp.password", "hunter3"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the history of the secrets is listed masked
+	entries, err := unmaskedHistory(store, ctx, "smtp.password")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetOldValue() != "hunter2" || entries[0].GetNewValue() != "hunter3" {
+		t.Fatal("History MUST be decrypted on read, found:", entries)
+	}
+
+	rawHistory := ""
+
+	if err := db.QueryRow(`SELECT new_value FROM setting_history WHERE setting_key = 'smtp.password' AND action = 'update'`).Scan(&rawHistory); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !strings.HasPrefix(rawHistory, "enc:v1:k1:") {
+		t.Fatal("History of secrets MUST be saved encrypted, found:", rawHistory)
+	}
+
+	if _, err := store.Increment(ctx, "smtp.port", 1); err == nil {
+		t.Fatal("Secret settings MUST NOT be incremented")
+	}
+}
+
+func TestStoreEncryptionTampered(t *testing.T) {
+	db, _ := initSharedMemoryStore(t)
+
+	store := initEncryptedStore(t, db, map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)}, "k1")
+
+	ctx := context.Background()
+
+	if err := store.SetMany(ctx, map[string]string{"smtp.password": "hunter2", "smtp.user": "mailer"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the ciphertext of the password is copied over the user
+	if _, err := db.Exec(`UPDATE setting SET setting_value = ? WHERE setting_key = 'smtp.user'`, rawSettingValue(t, db, "smtp.password")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.Get(ctx, "smtp.user", ""); !errors.Is(err, ErrDecryption) {
+		t.Fatal("Ciphertext of another setting MUST NOT be decrypted, found:", err)
+	}
+}
+
+func TestStoreReencryptAll(t *testing.T) {
+	db, plainStore := initSharedMemoryStore(t)
+
+	ctx := context.Background()
+
+	// saved before the key became secret
+	if err := plainStore.Set(ctx, "api.token", "legacy"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	oldKey := bytes.Repeat([]byte("a"), 32)
+	newKey := bytes.Repeat([]byte("b"), 32)
+
+	store := initEncryptedStore(t, db, map[string][]byte{"k1": oldKey}, "k1")
+
+	if value, err := store.Get(ctx, "api.token", ""); err != nil || value != "legacy" {
+		t.Fatal("Plaintext secret MUST be read as it is, found:", value, err)
+	}
+
+	for _, value := range []string{"hunter2", "hunter3"} {
+		if err := store.Set(ctx, "smtp.password", value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	rotatingStore := initEncryptedStore(t, db, map[string][]byte{"k1": oldKey, "k2": newKey}, "k1")
+
+	if _, err := rotatingStore.ReencryptAll(ctx, "k3"); err == nil {
+		t.Fatal("Key missing from the keyring MUST be rejected")
+	}
+
+	for _, view := range []StoreInterface{rotatingStore.ForTenant("acme"), rotatingStore.Namespace("smtp")} {
+		if _, err := view.ReencryptAll(ctx, "k2"); !errors.Is(err, errReencryptScoped) {
+			t.Fatal("Scoped view MUST NOT reseal all the settings, found:", err)
+		}
+	}
+
+	if keyID := activeEncryptionKeyID(rotatingStore); keyID != "k1" {
+		t.Fatal("Scoped view MUST NOT change the active key, found:", keyID)
+	}
+
+	resealed, err := rotatingStore.ReencryptAll(ctx, "k2")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the two settings, and their three changes in the history
+	if resealed != 5 {
+		t.Fatal("All the secrets MUST be resealed, found:", resealed)
+	}
+
+	for _, key := range []string{"api.token", "smtp.password"} {
+		if raw := rawSettingValue(t, db, key); !strings.HasPrefix(raw, "enc:v1:k2:") {
+			t.Fatalf("Secret %q MUST be sealed with the new key, found: %q", key, raw)
+		}
+	}
+
+	if err := rotatingStore.Set(ctx, "smtp.host", "mail.example.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if raw := rawSettingValue(t, db, "smtp.host"); !strings.HasPrefix(raw, "enc:v1:k2:") {
+		t.Fatal("New secrets MUST be sealed with the new key, found:", raw)
+	}
+
+	// the old key is retired
+	newStore := initEncryptedStore(t, db, map[string][]byte{"k2": newKey}, "k2")
+
+	value, err := newStore.Get(ctx, "smtp.password", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "hunter3" {
+		t.Fatal("Resealed secret MUST be decrypted with the new key, found:", value)
+	}
+
+	if value, found, err := newStore.GetAt(ctx, "api.token", time.Now()); err != nil || !found || value != "legacy" {
+		t.Fatal("Resealed history MUST be decrypted with the new key, found:", value, found, err)
+	}
+
+	if resealed, err := rotatingStore.ReencryptAll(ctx, "k2"); err != nil || resealed != 0 {
+		t.Fatal("Resealed secrets MUST be skipped, found:", resealed, err)
+	}
+}
+
+func TestNewStoreEncryptionOptions(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	defer db.Close()
+
+	invalid := []NewStoreOptions{
+		{EncryptionKeys: map[string][]byte{"k1": []byte("short")}, EncryptionKeyID: "k1"},
+		{EncryptionKeys: map[string][]byte{"k:1": bytes.Repeat([]byte("a"), 32)}, EncryptionKeyID: "k:1"},
+		{EncryptionKeys: map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)}, EncryptionKeyID: "k2"},
+		{SecretKeyPrefixes: []string{"smtp."}},
+	}
+
+	for i, opts := range invalid {
+		opts.DB = db
+		opts.SettingTableName = "setting"
+
+		if _, err := NewStore(opts); err == nil {
+			t.Fatalf("Options %d MUST be rejected", i)
+		}
+	}
+}
+
+func TestStorePrefixedPlaintext(t *testing.T) {
+	db, plainStore := initSharedMemoryStore(t)
+
+	ctx := context.Background()
+
+	values := map[string]string{
+		"site.tagline": "enc:foo",
+		"site.footer":  "enc:raw:keep-me",
+	}
+
+	if err := plainStore.SetMany(ctx, values); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the store with the encryption, but without these keys as secret
+	store := initEncryptedStore(t, db, map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)}, "k1")
+
+	for key, expected := range values {
+		if raw := rawSettingValue(t, db, key); raw != expected {
+			t.Fatalf("Plaintext %q MUST be saved as it is, found: %q", key, raw)
+		}
+
+		for _, reader := range []StoreInterface{plainStore, store} {
+			if value, err := reader.Get(ctx, key, ""); err != nil || value != expected {
+				t.Fatalf("Plaintext %q MUST be read as it is, found: %q %v", key, value, err)
+			}
+		}
+	}
+}
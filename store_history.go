@@ -0,0 +1,428 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+var errHistoryNotEnabled = errors.New("settingstore: history is not enabled, set HistoryTableName")
+
+// historyInsertAttempts is the number of times a change is recorded again,
+// when the version it was recorded with is taken concurrently
+const historyInsertAttempts = 10
+
+// SQLCreateHistoryTable returns a SQL string for creating the history table
+func (store *store) SQLCreateHistoryTable() string {
+	sql := sb.NewBuilder(store.dbDriverName).
+		Table(store.historyTableName).
+		Column(sb.Column{
+			Name:       COLUMN_ID,
+			Type:       sb.COLUMN_TYPE_STRING,
+			Length:     40,
+			PrimaryKey: true,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_SETTING_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_TENANT_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_SETTING_KEY,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 255,
+		}).
+		Column(sb.Column{
+			Name:   COLUMN_ACTION,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 20,
+		}).
+		Column(sb.Column{
+			Name: COLUMN_OLD_VALUE,
+			Type: sb.COLUMN_TYPE_TEXT,
+		}).
+		Column(sb.Column{
+			Name: COLUMN_NEW_VALUE,
+			Type: sb.COLUMN_TYPE_TEXT,
+		}).
+		Column(sb.Column{
+			Name: COLUMN_VERSION,
+			Type: sb.COLUMN_TYPE_INTEGER,
+		}).
+		Column(sb.Column{
+			Name: COLUMN_CREATED_AT,
+			Type: sb.COLUMN_TYPE_DATETIME,
+		}).
+		CreateIfNotExists()
+
+	return sql
+}
+
+// SettingHistory returns the recorded changes of a setting
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - query: the query to filter and page the changes, nil for all of them
+//
+// Returns:
+// - []SettingHistoryEntryInterface - the changes, newest first unless sorted otherwise
+// - error - nil if no error, error otherwise
+func (store *store) SettingHistory(ctx context.Context, settingKey string, query SettingHistoryQueryInterface) ([]SettingHistoryEntryInterface, error) {
+	entries, err := store.settingHistory(ctx, settingKey, query)
+
+	if err != nil || !store.isSensitiveKey(store.namespacedKey(settingKey)) {
+		return entries, err
+	}
+
+	return lo.Map(entries, func(entry SettingHistoryEntryInterface, _ int) SettingHistoryEntryInterface {
+		return NewSettingHistoryEntryFromExistingData(redactRow(entry.Data(), COLUMN_OLD_VALUE, COLUMN_NEW_VALUE))
+	}), nil
+}
+
+// settingHistory returns the changes recorded for the key, with the values unmasked
+func (store *store) settingHistory(ctx context.Context, settingKey string, query SettingHistoryQueryInterface) ([]SettingHistoryEntryInterface, error) {
+	if store.historyTableName == "" {
+		return nil, errHistoryNotEnabled
+	}
+
+	if settingKey == "" {
+		return nil, errors.New("settingstore > setting history. key cannot be empty")
+	}
+
+	if query == nil {
+		query = SettingHistoryQuery()
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	q := goqu.Dialect(store.dbDriverName).
+		From(store.historyTableName).
+		Prepared(true).
+		Where(goqu.C(COLUMN_TENANT_ID).Eq(store.tenantID)).
+		Where(goqu.C(COLUMN_SETTING_KEY).Eq(store.namespacedKey(settingKey)))
+
+	if query.HasCreatedAtGte() {
+		q = q.Where(goqu.C(COLUMN_CREATED_AT).Gte(query.CreatedAtGte()))
+	}
+
+	if query.HasCreatedAtLte() {
+		q = q.Where(goqu.C(COLUMN_CREATED_AT).Lte(query.CreatedAtLte()))
+	}
+
+	if query.HasSortOrder() && strings.EqualFold(query.SortOrder(), sb.ASC) {
+		q = q.Order(goqu.C(COLUMN_CREATED_AT).Asc(), goqu.C(COLUMN_VERSION).Asc())
+	} else {
+		q = q.Order(goqu.C(COLUMN_CREATED_AT).Desc(), goqu.C(COLUMN_VERSION).Desc())
+	}
+
+	if query.HasLimit() {
+		q = q.Limit(uint(query.Limit()))
+	}
+
+	if query.HasOffset() {
+		q = q.Offset(uint(query.Offset()))
+	}
+
+	sqlStr, params, errSql := q.ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("history", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []SettingHistoryEntryInterface{}
+
+	for _, row := range rows {
+		entry, err := store.historyEntryFromRow(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// historyEntryFromRow returns the recorded change of the row of the history
+// table, with the key as seen through the namespace, and the values opened
+// if the key is secret
+func (store *store) historyEntryFromRow(row map[string]string) (SettingHistoryEntryInterface, error) {
+	for _, column := range []string{COLUMN_OLD_VALUE, COLUMN_NEW_VALUE} {
+		value, err := store.openValue(row[COLUMN_TENANT_ID], row[COLUMN_SETTING_KEY], row[column])
+
+		if err != nil {
+			return nil, err
+		}
+
+		row[column] = value
+	}
+
+	row[COLUMN_SETTING_KEY] = store.namespaceStrip(row[COLUMN_SETTING_KEY])
+
+	return NewSettingHistoryEntryFromExistingData(row), nil
+}
+
+// GetAt returns the value a setting had at a past time, from the history
+//
+// The expiry of the settings is not taken into account. The history only
+// goes back to the moment it was enabled, when the existing settings are
+// recorded as created at their last update
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - at: the time
+//
+// Returns:
+// - string - the value of the setting at the time
+// - bool - true if the setting existed at the time, false otherwise
+// - error - nil if no error, error otherwise
+func (store *store) GetAt(ctx context.Context, settingKey string, at time.Time) (string, bool, error) {
+	entries, err := store.settingHistory(ctx, settingKey, SettingHistoryQuery().
+		SetCreatedAtLte(carbon.CreateFromStdTime(at, carbon.UTC).ToDateTimeString(carbon.UTC)).
+		SetLimit(1))
+
+	if err != nil {
+		return "", false, err
+	}
+
+	if len(entries) < 1 || entries[0].IsDeletion() {
+		return "", false, nil
+	}
+
+	return entries[0].GetNewValue(), true, nil
+}
+
+// historyVersionIndexName returns the name of the unique index
+// of the versions of the keys
+func (store *store) historyVersionIndexName() string {
+	return store.historyTableName + "_version_unique"
+}
+
+// migrateHistoryTable creates the history table, and the unique index of
+// the versions of the keys, if they do not exist. When the history is
+// enabled on existing settings, they are recorded as created at their
+// last update, so that they can be rolled back to
+func (st *store) migrateHistoryTable(ctx context.Context) error {
+	sqlStr := st.SQLCreateHistoryTable()
+
+	if sqlStr == "" {
+		return errors.New("setting store: history table create sql is empty")
+	}
+
+	if _, err := database.Execute(database.Context(ctx, st.db), sqlStr); err != nil {
+		return err
+	}
+
+	exists, err := st.indexExists(ctx, st.historyTableName, st.historyVersionIndexName())
+
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		sqlStr = sqlCreateUniqueIndex(st.dbDriverName, st.historyVersionIndexName(), st.historyTableName,
+			[]string{COLUMN_TENANT_ID, COLUMN_SETTING_KEY, COLUMN_VERSION}, "")
+
+		st.logSql("migrate", sqlStr)
+
+		if _, err := database.Execute(database.Context(ctx, st.db), sqlStr); err != nil {
+			return err
+		}
+	}
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		sqlStr, params, errSql := goqu.Dialect(txStore.dbDriverName).
+			From(txStore.historyTableName).
+			Prepared(true).
+			Select(goqu.COUNT(goqu.Star()).As("count")).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		rows, err := txStore.selectToMapString(ctx, sqlStr, params...)
+
+		if err != nil {
+			return err
+		}
+
+		if len(rows) > 0 && rows[0]["count"] != "0" {
+			return nil // the history was started already
+		}
+
+		settings, err := txStore.loadChangedRows(ctx, goqu.C(COLUMN_SOFT_DELETED_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)))
+
+		if err != nil {
+			return err
+		}
+
+		return txStore.historyRecord(ctx, lo.Map(settings, func(row map[string]string, _ int) recordedChange {
+			return recordedChange{
+				settingID: row[COLUMN_ID],
+				tenantID:  row[COLUMN_TENANT_ID],
+				key:       row[COLUMN_SETTING_KEY],
+				action:    HISTORY_ACTION_CREATE,
+				newValue:  row[COLUMN_SETTING_VALUE],
+				createdAt: carbon.Parse(row[COLUMN_UPDATED_AT], carbon.UTC).ToDateTimeString(carbon.UTC),
+			}
+		})...)
+	})
+}
+
+// historyRecord inserts the changes into the history table,
+// in the transaction the store is bound to. It does nothing
+// if the history is not enabled
+func (st *store) historyRecord(ctx context.Context, changes ...recordedChange) error {
+	if st.historyTableName == "" {
+		return nil
+	}
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		for _, change := range changes {
+			if err := txStore.historyInsert(ctx, change); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// historyInsert inserts the change into the history table, with the version
+// following the version of the last recorded change of the key
+//
+// The versions of a key are unique, so of two transactions recording a
+// change of the same key at the same time, the insert of the second one
+// fails. It is rolled back to a savepoint then, and the change is recorded
+// again with the next version. Without savepoints, the insert fails
+func (st *store) historyInsert(ctx context.Context, change recordedChange) error {
+	_, _, _, errSavepoint := savepointSqls(st.dbDriverName, "history")
+
+	createdAt := lo.Ternary(change.createdAt != "", change.createdAt, carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
+
+	entry := newSettingHistoryEntry()
+	entry.Set(COLUMN_SETTING_ID, change.settingID)
+	entry.Set(COLUMN_TENANT_ID, change.tenantID)
+	entry.Set(COLUMN_SETTING_KEY, change.key)
+	entry.Set(COLUMN_ACTION, change.action)
+	entry.Set(COLUMN_OLD_VALUE, change.oldValue)
+	entry.Set(COLUMN_NEW_VALUE, change.newValue)
+	entry.Set(COLUMN_CREATED_AT, createdAt)
+
+	for attempt := 0; attempt < historyInsertAttempts; attempt++ {
+		version, err := st.historyLastVersion(ctx, change.tenantID, change.key)
+
+		if err != nil {
+			return err
+		}
+
+		entry.Set(COLUMN_VERSION, strconv.FormatInt(version+1, 10))
+
+		sqlStr, params, errSql := goqu.Dialect(st.dbDriverName).
+			Insert(st.historyTableName).
+			Prepared(true).
+			Rows(entry.Data()).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		st.logSql("history", sqlStr, st.redactParams(params, change.key, change.oldValue, change.newValue)...)
+
+		if errSavepoint != nil {
+			_, err := st.executeSql(ctx, sqlStr, params...)
+			return err
+		}
+
+		errInsert := st.inSavepoint(ctx, func(txStore *store) error {
+			_, err := txStore.executeSql(ctx, sqlStr, params...)
+			return err
+		})
+
+		if errInsert == nil {
+			return nil
+		}
+
+		// recorded again only if the version was taken concurrently
+		lastVersion, err := st.historyLastVersion(ctx, change.tenantID, change.key)
+
+		if err != nil {
+			return errors.Join(errInsert, err)
+		}
+
+		if lastVersion <= version {
+			return errInsert
+		}
+	}
+
+	return ErrConflict
+}
+
+// historyLastVersion returns the version of the last recorded change of the key
+//
+// On MySQL and Postgres the change is locked, so that the transactions
+// recording a change of the key wait for each other, and read the last
+// committed change instead of the snapshot of the transaction
+func (store *store) historyLastVersion(ctx context.Context, tenantID string, key string) (int64, error) {
+	query := goqu.Dialect(store.dbDriverName).
+		From(store.historyTableName).
+		Prepared(true).
+		Select(COLUMN_VERSION).
+		Where(goqu.C(COLUMN_TENANT_ID).Eq(tenantID)).
+		Where(goqu.C(COLUMN_SETTING_KEY).Eq(key)).
+		Order(goqu.C(COLUMN_VERSION).Desc()).
+		Limit(1)
+
+	if lo.Contains([]string{sb.DIALECT_MYSQL, sb.DIALECT_POSTGRES}, store.dbDriverName) {
+		query = query.ForUpdate(exp.Wait)
+	}
+
+	sqlStr, params, errSql := query.ToSQL()
+
+	if errSql != nil {
+		return 0, errSql
+	}
+
+	store.logSql("history", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rows) < 1 || rows[0][COLUMN_VERSION] == "" {
+		return 0, nil // no changes recorded yet
+	}
+
+	return strconv.ParseInt(rows[0][COLUMN_VERSION], 10, 64)
+}
@@ -0,0 +1,394 @@
+package settingstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/dromara/carbon/v2"
+)
+
+func initHistoryStore(t *testing.T) (*sql.DB, StoreInterface) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		HistoryTableName:   "setting_history",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	return db, store
+}
+
+func TestStoreSettingHistory(t *testing.T) {
+	_, store := initHistoryStore(t)
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "mail.from", "a@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "mail.from", "b@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// an unchanged value is not recorded
+	if err := store.Set(ctx, "mail.from", "b@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	setting, err := store.SettingFindByKey(ctx, "mail.from")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SettingSoftDelete(ctx, setting); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "mail.from", "c@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Delete(ctx, "mail.from"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	entries, err := store.SettingHistory(ctx, "mail.from", SettingHistoryQuery().SetSortOrder("asc"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := []struct {
+		action   string
+		oldValue string
+		newValue string
+	}{
+		{HISTORY_ACTION_CREATE, "", "a@test.com"},
+		{HISTORY_ACTION_UPDATE, "a@test.com", "b@test.com"},
+		{HISTORY_ACTION_SOFT_DELETE, "b@test.com", ""},
+		{HISTORY_ACTION_CREATE, "", "c@test.com"},
+		{HISTORY_ACTION_DELETE, "c@test.com", ""},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatal("History MUST have 5 entries, found:", len(entries))
+	}
+
+	for i, entry := range entries {
+		if entry.GetAction() != expected[i].action || entry.GetOldValue() != expected[i].oldValue || entry.GetNewValue() != expected[i].newValue {
+			t.Fatalf("unexpected entry %d: %v", i, entry.Data())
+		}
+
+		if entry.GetVersion() != int64(i+1) {
+			t.Fatal("Entry versions MUST be sequential, found:", entry.GetVersion())
+		}
+
+		if entry.GetKey() != "mail.from" {
+			t.Fatal("unexpected key:", entry.GetKey())
+		}
+	}
+
+	entries, err = store.SettingHistory(ctx, "mail.from", SettingHistoryQuery().SetLimit(1))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 1 || entries[0].GetAction() != HISTORY_ACTION_DELETE {
+		t.Fatal("History MUST be sorted newest first by default")
+	}
+}
+
+func TestStoreSettingHistoryByPrefix(t *testing.T) {
+	_, store := initHistoryStore(t)
+
+	ctx := context.Background()
+
+	billing := store.ForTenant("acme").Namespace("billing")
+
+	for _, key := range []string{"currency", "vat"} {
+		if err := billing.Set(ctx, key, "value"); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if _, err := billing.SoftDeleteByPrefix(ctx, "c"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := billing.DeleteByPrefix(ctx, "v"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	entries, err := billing.SettingHistory(ctx, "currency", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetAction() != HISTORY_ACTION_SOFT_DELETE || entries[0].GetTenantID() != "acme" {
+		t.Fatal("SoftDeleteByPrefix MUST be recorded, found:", len(entries))
+	}
+
+	entries, err = billing.SettingHistory(ctx, "vat", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetAction() != HISTORY_ACTION_DELETE {
+		t.Fatal("DeleteByPrefix MUST be recorded, found:", len(entries))
+	}
+
+	entries, err = store.SettingHistory(ctx, "billing.vat", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatal("The history of another tenant MUST NOT be visible, found:", len(entries))
+	}
+}
+
+func TestStoreGetAt(t *testing.T) {
+	db, store := initHistoryStore(t)
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "mail.from", "a@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "mail.from", "b@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// move the first version a week back in time
+	weekAgo := carbon.Now(carbon.UTC).SubDays(7)
+	sixDaysAgo := carbon.Now(carbon.UTC).SubDays(6).StdTime()
+	eightDaysAgo := carbon.Now(carbon.UTC).SubDays(8).StdTime()
+
+	if _, err := db.Exec(`UPDATE setting_history SET created_at = ? WHERE version = 1`, weekAgo.ToDateTimeString(carbon.UTC)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, found, err := store.GetAt(ctx, "mail.from", sixDaysAgo)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !found || value != "a@test.com" {
+		t.Fatal("GetAt MUST return the value of the past, found:", value)
+	}
+
+	value, found, err = store.GetAt(ctx, "mail.from", time.Now())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !found || value != "b@test.com" {
+		t.Fatal("GetAt MUST return the current value for now, found:", value)
+	}
+
+	_, found, err = store.GetAt(ctx, "mail.from", eightDaysAgo)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found {
+		t.Fatal("GetAt MUST NOT find a setting before it was created")
+	}
+
+	if err := store.Delete(ctx, "mail.from"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, found, err = store.GetAt(ctx, "mail.from", time.Now())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found {
+		t.Fatal("GetAt MUST NOT find a deleted setting")
+	}
+}
+
+func TestStoreHistoryNotEnabled(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	_, _, err = store.GetAt(context.Background(), "mail.from", time.Now())
+
+	if !errors.Is(err, errHistoryNotEnabled) {
+		t.Fatal("GetAt MUST fail if the history is not enabled, found:", err)
+	}
+}
+
+func TestStoreHistoryBaseline(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	ctx := context.Background()
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	if err := store.Set(ctx, "mail.from", "a@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	historyStore, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		HistoryTableName:   "setting_history",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	value, found, err := historyStore.GetAt(ctx, "mail.from", time.Now())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !found || value != "a@test.com" {
+		t.Fatal("The existing settings MUST be recorded when the history is enabled, found:", value)
+	}
+}
+
+func TestStoreHistoryConcurrentWriters(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	hook := &sqlHook{prefix: `INSERT INTO "setting_history"`}
+
+	opts := NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		HistoryTableName:   "setting_history",
+		AutomigrateEnabled: true,
+	}
+
+	otherStore, err := NewStore(opts)
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	opts.DebugEnabled = true
+	opts.SqlLogger = slog.New(hook)
+
+	store, err := NewStore(opts)
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "app.name", "a"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the other writer records a change of the key with the same version first
+	var errOther error
+
+	hook.fn = func() {
+		errOther = otherStore.WithTx(tx).Set(ctx, "app.name", "c")
+	}
+
+	if err := store.WithTx(tx).Set(ctx, "app.name", "b"); err != nil {
+		t.Fatal("Change MUST be recorded again when its version is taken, found:", err)
+	}
+
+	if errOther != nil {
+		t.Fatal("unexpected error:", errOther)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	entries, err := store.SettingHistory(ctx, "app.name", SettingHistoryQuery().SetSortOrder("asc"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatal("All the changes MUST be recorded, found:", len(entries))
+	}
+
+	for i, entry := range entries {
+		if entry.GetVersion() != int64(i+1) {
+			t.Fatal("Versions MUST be unique, found:", entry.GetVersion())
+		}
+	}
+}
+
+func TestStoreHistoryVersionUnique(t *testing.T) {
+	db, store := initHistoryStore(t)
+
+	if err := store.Set(context.Background(), "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err := db.Exec(`INSERT INTO setting_history (id, setting_id, tenant_id, setting_key, action, old_value, new_value, version, created_at)
+		VALUES ('duplicate', '', '', 'app.name', 'update', '', '', 1, '2025-01-01 10:00:00')`)
+
+	if err == nil {
+		t.Fatal("Versions of a key MUST be unique")
+	}
+}
@@ -2,6 +2,8 @@ package settingstore
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 // StoreInterface defines the interface for a setting store.
@@ -15,6 +17,21 @@ type StoreInterface interface {
 	// - error - nil if no error, error otherwise
 	AutoMigrate(ctx context.Context) error
 
+	// CacheStats returns the hit and miss counters of the setting cache
+	//
+	// Returns:
+	// - CacheStats - the cache counters, all zero if the cache is not enabled
+	CacheStats() CacheStats
+
+	// Close stops the background expiry sweeper, if it was started
+	//
+	// Parameters:
+	// - ctx: the context
+	//
+	// Returns:
+	// - error - nil if no error, the context error if it was done first
+	Close(ctx context.Context) error
+
 	// EnableDebug - enables the debug option
 	//
 	// # If enabled will log the SQL statements to the provided logger
@@ -26,6 +43,53 @@ type StoreInterface interface {
 	//   - void
 	EnableDebug(debug bool)
 
+	// ForTenant returns a view of the store scoped to the tenant
+	//
+	// The view can neither read nor write the settings of another tenant,
+	// and cannot be rescoped. The keys are unique per tenant
+	//
+	// Parameters:
+	// - tenantID: the ID of the tenant
+	//
+	// Returns:
+	// - StoreInterface - the tenant scoped view of the store
+	ForTenant(tenantID string) StoreInterface
+
+	// Namespace returns a view of the store scoped to the namespace
+	//
+	// Every key is prefixed with the namespace and a dot on write, and
+	// stripped on read. The view never sees the settings outside the
+	// namespace. Views nest, i.e. Namespace("billing").Namespace("stripe")
+	//
+	// Parameters:
+	// - namespace: the namespace, an empty namespace returns the store itself
+	//
+	// Returns:
+	// - StoreInterface - the namespaced view of the store
+	Namespace(namespace string) StoreInterface
+
+	// PurgeExpired hard deletes the settings which have expired
+	//
+	// Parameters:
+	// - ctx: the context
+	//
+	// Returns:
+	// - int64 - the number of settings removed
+	// - error - nil if no error, error otherwise
+	PurgeExpired(ctx context.Context) (int64, error)
+
+	// PurgeSoftDeleted hard deletes the settings which were soft deleted
+	// more than the specified duration ago
+	//
+	// Parameters:
+	// - ctx: the context
+	// - olderThan: how long ago the settings must have been soft deleted
+	//
+	// Returns:
+	// - int64 - the number of settings removed
+	// - error - nil if no error, error otherwise
+	PurgeSoftDeleted(ctx context.Context, olderThan time.Duration) (int64, error)
+
 	// SettingCount counts the settings based on the provided query.
 	//
 	// Parameters:
@@ -120,14 +184,14 @@ type StoreInterface interface {
 	// - error: nil if no error, error otherwise
 	SettingSoftDeleteByID(ctx context.Context, settingID string) error
 
-	// SettingUpdate updates a setting
+	// SettingUpdate updates a setting, if it still has the version it was loaded with
 	//
 	// Parameters:
 	// - ctx: the context
 	// - setting: the setting
 	//
 	// Returns:
-	// - error: nil if no error, error otherwise
+	// - error: nil if no error, ErrConflict if the setting was changed since it was loaded, error otherwise
 	SettingUpdate(ctx context.Context, setting SettingInterface) error
 
 	// Delete is a shortcut method to delete a value by key
@@ -140,6 +204,39 @@ type StoreInterface interface {
 	// - error - nil if no error, error otherwise
 	Delete(ctx context.Context, settingKey string) error
 
+	// DeleteMany hard deletes the settings by key, in a single transaction
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKeys: the keys of the settings to delete
+	//
+	// Returns:
+	// - int64 - the number of settings deleted
+	// - error - nil if no error, error otherwise
+	DeleteMany(ctx context.Context, settingKeys []string) (int64, error)
+
+	// DeleteByPrefix hard deletes the settings with keys starting with the prefix
+	//
+	// Parameters:
+	// - ctx: the context
+	// - keyPrefix: the prefix of the keys to delete, i.e. "mail.smtp."
+	//
+	// Returns:
+	// - int64 - the number of settings deleted
+	// - error - nil if no error, error otherwise
+	DeleteByPrefix(ctx context.Context, keyPrefix string) (int64, error)
+
+	// SoftDeleteByPrefix soft deletes the settings with keys starting with the prefix
+	//
+	// Parameters:
+	// - ctx: the context
+	// - keyPrefix: the prefix of the keys to soft delete, i.e. "mail.smtp."
+	//
+	// Returns:
+	// - int64 - the number of settings soft deleted
+	// - error - nil if no error, error otherwise
+	SoftDeleteByPrefix(ctx context.Context, keyPrefix string) (int64, error)
+
 	// Get is a shortcut method to get a value by key, or a default, if not found
 	//
 	// Parameters:
@@ -152,6 +249,17 @@ type StoreInterface interface {
 	// - error - nil if no error, error otherwise
 	Get(ctx context.Context, settingKey string, valueDefault string) (string, error)
 
+	// GetMany gets the values of the settings by key, with a single query
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKeys: the keys of the settings to get
+	//
+	// Returns:
+	// - map[string]string - the values by key, without the settings not found
+	// - error - nil if no error, error otherwise
+	GetMany(ctx context.Context, settingKeys []string) (map[string]string, error)
+
 	// GetAny is a shortcut method to get a value by key as an interface, or a default if not found
 	//
 	// Parameters:
@@ -208,12 +316,104 @@ type StoreInterface interface {
 	// - error - nil if no error, error otherwise
 	Set(ctx context.Context, settingKey string, value string) error
 
+	// SetWithTTL is a shortcut method to save a value by key, which expires
+	// after the specified number of seconds, use Get to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	// - seconds: the seconds until the setting expires, 0 or less never expires
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetWithTTL(ctx context.Context, settingKey string, value string, seconds int64) error
+
+	// SetMany saves the values by key, in a single transaction.
+	// The saved settings never expire
+	//
+	// Parameters:
+	// - ctx: the context
+	// - values: the values to save, by key
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetMany(ctx context.Context, values map[string]string) error
+
+	// CompareAndSet atomically replaces the value of a setting, only if it
+	// still has the expected value
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - expectedValue: the value the setting must have
+	// - newValue: the value to save
+	//
+	// Returns:
+	// - bool - true if the value was replaced, false otherwise
+	// - error - nil if no error, error otherwise
+	CompareAndSet(ctx context.Context, settingKey string, expectedValue string, newValue string) (bool, error)
+
+	// Increment atomically adds the delta to the integer value of a setting,
+	// creating the setting with the delta as its value if it does not exist
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - delta: the number to add, negative to subtract
+	//
+	// Returns:
+	// - int64 - the new value of the setting
+	// - error - a *ParseError if the value is not an integer, nil if no error, error otherwise
+	Increment(ctx context.Context, settingKey string, delta int64) (int64, error)
+
+	// Decrement atomically subtracts the delta from the integer value of a setting,
+	// creating the setting with the negated delta as its value if it does not exist
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - delta: the number to subtract, negative to add, math.MinInt64 is not allowed
+	//
+	// Returns:
+	// - int64 - the new value of the setting
+	// - error - a *ParseError if the value is not an integer, nil if no error, error otherwise
+	Decrement(ctx context.Context, settingKey string, delta int64) (int64, error)
+
+	// SetIfAbsent saves the value by key, only if the setting does not exist,
+	// of concurrent callers exactly one creates it
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - value: the value to save, if the setting does not exist
+	//
+	// Returns:
+	// - stored - the value of the setting, the one of the caller if created
+	// - created - true if the setting was created by this call, false otherwise
+	// - err - nil if no error, error otherwise
+	SetIfAbsent(ctx context.Context, settingKey string, value string) (stored string, created bool, err error)
+
+	// GetOrCompute gets the value by key, or computes and saves it, if the
+	// setting does not exist, concurrent callers wait for a single computation
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - compute: the function computing the value of a missing setting
+	//
+	// Returns:
+	// - string - the value of the setting
+	// - error - nil if no error, the error of compute, error otherwise
+	GetOrCompute(ctx context.Context, settingKey string, compute func() (string, error)) (string, error)
+
 	// SetAny is a shortcut method to save any value by key, use GetAny to extract
 	//
 	// Parameters:
 	// - ctx: the context
 	// - settingKey: the key of the setting to save
 	// - value: the value to save
+	// - seconds: the seconds until the setting expires, 0 or less never expires
 	//
 	// Returns:
 	// - error - nil if no error, error otherwise
@@ -230,6 +430,19 @@ type StoreInterface interface {
 	// - error - nil if no error, error otherwise
 	SetMap(ctx context.Context, key string, value map[string]any) error
 
+	// SetMapWithTTL is a shortcut method to save a map by key, which expires
+	// after the specified number of seconds, use GetMap to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	// - seconds: the seconds until the setting expires, 0 or less never expires
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetMapWithTTL(ctx context.Context, key string, value map[string]any, seconds int64) error
+
 	// SettingDeleteByKey deletes a setting by id
 	//
 	// Parameters:
@@ -239,4 +452,334 @@ type StoreInterface interface {
 	// Returns:
 	// - error - nil if no error, error otherwise
 	SettingDeleteByKey(ctx context.Context, settingKey string) error
+
+	// GetInt is a shortcut method to get a value by key as an int, or a default if not found
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to get
+	// - valueDefault: the default value to return if the setting is not found
+	//
+	// Returns:
+	// - int - the value of the setting, or the default value if not found
+	// - error - a *ParseError if the value is not an int, nil if no error
+	GetInt(ctx context.Context, settingKey string, valueDefault int) (int, error)
+
+	// GetInt64 is a shortcut method to get a value by key as an int64, or a default if not found
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to get
+	// - valueDefault: the default value to return if the setting is not found
+	//
+	// Returns:
+	// - int64 - the value of the setting, or the default value if not found
+	// - error - a *ParseError if the value is not an int64, nil if no error
+	GetInt64(ctx context.Context, settingKey string, valueDefault int64) (int64, error)
+
+	// GetBool is a shortcut method to get a value by key as a bool, or a default if not found
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to get
+	// - valueDefault: the default value to return if the setting is not found
+	//
+	// Returns:
+	// - bool - the value of the setting, or the default value if not found
+	// - error - a *ParseError if the value is not a bool, nil if no error
+	GetBool(ctx context.Context, settingKey string, valueDefault bool) (bool, error)
+
+	// GetFloat64 is a shortcut method to get a value by key as a float64, or a default if not found
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to get
+	// - valueDefault: the default value to return if the setting is not found
+	//
+	// Returns:
+	// - float64 - the value of the setting, or the default value if not found
+	// - error - a *ParseError if the value is not a float64, nil if no error
+	GetFloat64(ctx context.Context, settingKey string, valueDefault float64) (float64, error)
+
+	// GetDuration is a shortcut method to get a value by key as a duration, or a default if not found
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to get
+	// - valueDefault: the default value to return if the setting is not found
+	//
+	// Returns:
+	// - time.Duration - the value of the setting, or the default value if not found
+	// - error - a *ParseError if the value is not a duration, nil if no error
+	GetDuration(ctx context.Context, settingKey string, valueDefault time.Duration) (time.Duration, error)
+
+	// GetTime is a shortcut method to get a value by key as a time, or a default if not found
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to get
+	// - valueDefault: the default value to return if the setting is not found
+	//
+	// Returns:
+	// - time.Time - the value of the setting, or the default value if not found
+	// - error - a *ParseError if the value is not a time, nil if no error
+	GetTime(ctx context.Context, settingKey string, valueDefault time.Time) (time.Time, error)
+
+	// GetStringSlice is a shortcut method to get a value by key as a string slice, or a default if not found
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to get
+	// - valueDefault: the default value to return if the setting is not found
+	//
+	// Returns:
+	// - []string - the value of the setting, or the default value if not found
+	// - error - a *ParseError if the value is not a string slice, nil if no error
+	GetStringSlice(ctx context.Context, settingKey string, valueDefault []string) ([]string, error)
+
+	// SetInt is a shortcut method to save an int by key, use GetInt to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetInt(ctx context.Context, settingKey string, value int) error
+
+	// SetInt64 is a shortcut method to save an int64 by key, use GetInt64 to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetInt64(ctx context.Context, settingKey string, value int64) error
+
+	// SetBool is a shortcut method to save a bool by key, use GetBool to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetBool(ctx context.Context, settingKey string, value bool) error
+
+	// SetFloat64 is a shortcut method to save a float64 by key, use GetFloat64 to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetFloat64(ctx context.Context, settingKey string, value float64) error
+
+	// SetDuration is a shortcut method to save a duration by key, use GetDuration to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetDuration(ctx context.Context, settingKey string, value time.Duration) error
+
+	// SetTime is a shortcut method to save a time by key, use GetTime to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetTime(ctx context.Context, settingKey string, value time.Time) error
+
+	// SetStringSlice is a shortcut method to save a string slice by key, use GetStringSlice to extract
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting to save
+	// - value: the value to save
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	SetStringSlice(ctx context.Context, settingKey string, value []string) error
+
+	// SettingHistory returns the recorded changes of a setting,
+	// requires HistoryTableName to be set
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - query: the query to filter and page the changes, nil for all of them
+	//
+	// Returns:
+	// - []SettingHistoryEntryInterface - the changes, newest first unless sorted otherwise
+	// - error - nil if no error, error otherwise
+	SettingHistory(ctx context.Context, settingKey string, query SettingHistoryQueryInterface) ([]SettingHistoryEntryInterface, error)
+
+	// GetAt returns the value a setting had at a past time, from the history,
+	// requires HistoryTableName to be set
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - at: the time
+	//
+	// Returns:
+	// - string - the value of the setting at the time
+	// - bool - true if the setting existed at the time, false otherwise
+	// - error - nil if no error, error otherwise
+	GetAt(ctx context.Context, settingKey string, at time.Time) (string, bool, error)
+
+	// Rollback restores a setting to its state at a version or a time, from
+	// the history, in one transaction recorded as new changes. Requires
+	// HistoryTableName to be set
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	// - versionOrTime: the version of a recorded change (int or int64), or a time.Time
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	Rollback(ctx context.Context, settingKey string, versionOrTime any) error
+
+	// RollbackPrefix restores the settings with keys starting with the prefix
+	// to their state at a time, from the history, in one transaction recorded
+	// as new changes. Requires HistoryTableName to be set
+	//
+	// Parameters:
+	// - ctx: the context
+	// - keyPrefix: the prefix of the keys, i.e. "mail.smtp."
+	// - at: the time to restore the settings to
+	//
+	// Returns:
+	// - int64 - the number of settings changed
+	// - error - nil if no error, error otherwise
+	RollbackPrefix(ctx context.Context, keyPrefix string, at time.Time) (int64, error)
+
+	// SettingAudit returns the changes recorded in the audit trail, with the
+	// actor, the reason and the request ID carried by the context of each
+	// change. Requires AuditTableName to be set
+	//
+	// Parameters:
+	// - ctx: the context
+	// - query: the query to filter and page the entries, nil for all of them
+	//
+	// Returns:
+	// - []SettingAuditEntryInterface - the entries, newest first unless sorted otherwise
+	// - error - nil if no error, error otherwise
+	SettingAudit(ctx context.Context, query SettingAuditQueryInterface) ([]SettingAuditEntryInterface, error)
+
+	// VerifyAuditChain walks the whole audit trail and checks that every
+	// entry is unchanged and chained to the previous one. Requires
+	// AuditTableName to be set
+	//
+	// Parameters:
+	// - ctx: the context
+	//
+	// Returns:
+	// - error - nil if the chain is intact, *AuditChainError for the first
+	// broken link, error otherwise
+	VerifyAuditChain(ctx context.Context) error
+
+	// RunInTransaction runs the function with a store bound to a transaction,
+	// committed if the function returns no error, rolled back otherwise.
+	// Nested calls run in a savepoint
+	//
+	// Parameters:
+	// - ctx: the context
+	// - fn: the function to run, with the store bound to the transaction
+	//
+	// Returns:
+	// - error - nil if no error, error otherwise
+	RunInTransaction(ctx context.Context, fn func(tx StoreInterface) error) error
+
+	// WithTx returns a view of the store bound to a transaction of the caller,
+	// who commits or rolls it back
+	//
+	// Parameters:
+	// - tx: the transaction
+	//
+	// Returns:
+	// - StoreInterface - the view of the store bound to the transaction
+	WithTx(tx *sql.Tx) StoreInterface
+
+	// AcquireLease acquires the named lease for the owner, for the duration
+	// of the TTL, if it is free, expired, or held by the owner already.
+	// Requires LeaseTableName to be set
+	//
+	// Parameters:
+	// - ctx: the context
+	// - leaseName: the name of the lease
+	// - owner: the unique name of the owner
+	// - ttl: how long the lease is held, unless renewed or released
+	//
+	// Returns:
+	// - bool - true if the owner holds the lease, false if another owner does
+	// - error - nil if no error, error otherwise
+	AcquireLease(ctx context.Context, leaseName string, owner string, ttl time.Duration) (bool, error)
+
+	// RenewLease extends the named lease held by the owner, for the duration
+	// of the TTL from now
+	//
+	// Parameters:
+	// - ctx: the context
+	// - leaseName: the name of the lease
+	// - owner: the owner holding the lease
+	// - ttl: how long the lease is held from now
+	//
+	// Returns:
+	// - bool - true if renewed, false if the owner does not hold the lease
+	// - error - nil if no error, error otherwise
+	RenewLease(ctx context.Context, leaseName string, owner string, ttl time.Duration) (bool, error)
+
+	// ReleaseLease releases the named lease held by the owner
+	//
+	// Parameters:
+	// - ctx: the context
+	// - leaseName: the name of the lease
+	// - owner: the owner holding the lease
+	//
+	// Returns:
+	// - bool - true if released, false if the owner does not hold the lease
+	// - error - nil if no error, error otherwise
+	ReleaseLease(ctx context.Context, leaseName string, owner string) (bool, error)
+
+	// ReencryptAll makes the key the one sealing the new values, and seals
+	// again with it the secret settings, and their history, sealed with
+	// another key. It covers all the tenants and the namespaces, and fails
+	// on a tenant scoped or a namespaced view
+	//
+	// Parameters:
+	// - ctx: the context
+	// - newKeyID: the ID of the key in the keyring to seal with
+	//
+	// Returns:
+	// - int64 - the number of rows resealed
+	// - error - nil if no error, error otherwise
+	ReencryptAll(ctx context.Context, newKeyID string) (int64, error)
+
+	// Reveal returns the unmasked value of a setting, and records in the
+	// audit trail that it was revealed, if it is sensitive
+	//
+	// Parameters:
+	// - ctx: the context
+	// - settingKey: the key of the setting
+	//
+	// Returns:
+	// - string - the value of the setting
+	// - bool - true if the setting was found, false otherwise
+	// - error - nil if no error, error otherwise
+	Reveal(ctx context.Context, settingKey string) (string, bool, error)
 }
@@ -0,0 +1,391 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/gouniverse/uid"
+)
+
+var errLeaseNotEnabled = errors.New("settingstore: leases are not enabled, set LeaseTableName")
+
+// SQLCreateLeaseTable returns a SQL string for creating the lease table
+func (store *store) SQLCreateLeaseTable() string {
+	builder := sb.NewBuilder(store.dbDriverName).
+		Table(store.leaseTableName)
+
+	for _, column := range store.leaseTableColumns() {
+		builder = builder.Column(column)
+	}
+
+	return builder.CreateIfNotExists()
+}
+
+// leaseTableColumns returns the columns of the lease table
+func (store *store) leaseTableColumns() []sb.Column {
+	return []sb.Column{
+		{
+			Name:       COLUMN_ID,
+			Type:       sb.COLUMN_TYPE_STRING,
+			Length:     40,
+			PrimaryKey: true,
+		},
+		{
+			Name:   COLUMN_TENANT_ID,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 40,
+		},
+		{
+			Name:   COLUMN_LEASE_NAME,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 255,
+		},
+		{
+			Name:   COLUMN_OWNER,
+			Type:   sb.COLUMN_TYPE_STRING,
+			Length: 255,
+		},
+		{
+			Name: COLUMN_VERSION,
+			Type: sb.COLUMN_TYPE_INTEGER,
+		},
+		{
+			Name: COLUMN_CREATED_AT,
+			Type: sb.COLUMN_TYPE_DATETIME,
+		},
+		{
+			Name: COLUMN_UPDATED_AT,
+			Type: sb.COLUMN_TYPE_DATETIME,
+		},
+		{
+			Name: COLUMN_EXPIRES_AT,
+			Type: sb.COLUMN_TYPE_DATETIME,
+		},
+	}
+}
+
+// leaseIndexName returns the name of the unique index of the lease names
+func (store *store) leaseIndexName() string {
+	return store.leaseTableName + "_name_unique"
+}
+
+// migrateLeaseTable creates the lease table, and the unique index
+// of the lease names, if they do not exist
+func (st *store) migrateLeaseTable(ctx context.Context) error {
+	sqlStr := st.SQLCreateLeaseTable()
+
+	if sqlStr == "" {
+		return errors.New("setting store: lease table create sql is empty")
+	}
+
+	if _, err := database.Execute(database.Context(ctx, st.db), sqlStr); err != nil {
+		return err
+	}
+
+	if err := st.migrateColumns(ctx, st.leaseTableName, st.leaseTableColumns(), nil); err != nil {
+		return err
+	}
+
+	exists, err := st.indexExists(ctx, st.leaseTableName, st.leaseIndexName())
+
+	if err != nil || exists {
+		return err
+	}
+
+	sqlStr = sqlCreateUniqueIndex(st.dbDriverName, st.leaseIndexName(), st.leaseTableName,
+		[]string{COLUMN_TENANT_ID, COLUMN_LEASE_NAME}, "")
+
+	st.logSql("migrate", sqlStr)
+
+	_, err = database.Execute(database.Context(ctx, st.db), sqlStr)
+
+	return err
+}
+
+// AcquireLease acquires the named lease for the owner, for the duration
+// of the TTL, i.e. to make sure a job runs on a single worker at a time
+//
+// The lease is taken with atomic conditional statements, so that of
+// concurrent owners only one acquires it. It is acquired if it is free,
+// expired, or already held by the owner, who extends it then. The expiry
+// is set and checked with the clock of the database, so that the clocks
+// of the workers may be skewed. It is rounded up to the second, and has
+// one second added, so that the lease is held for at least the TTL
+//
+// Parameters:
+// - ctx: the context
+// - leaseName: the name of the lease, i.e. "jobs.invoices"
+// - owner: the unique name of the owner, i.e. the host and the process ID
+// - ttl: how long the lease is held, unless renewed or released
+//
+// Returns:
+// - bool - true if the owner holds the lease, false if another owner does
+// - error - nil if no error, error otherwise
+func (st *store) AcquireLease(ctx context.Context, leaseName string, owner string, ttl time.Duration) (bool, error) {
+	if err := st.leaseValidate(leaseName, owner); err != nil {
+		return false, err
+	}
+
+	if ttl <= 0 {
+		return false, errors.New("settingstore > acquire lease. ttl must be positive")
+	}
+
+	now := st.leaseNowExpression()
+	expiresAt := st.leaseExpiresAtExpression(ttl)
+
+	// taken over if expired, extended if held by the owner already
+	acquired, err := st.leaseUpdate(ctx, leaseName, goqu.Record{
+		COLUMN_OWNER:      owner,
+		COLUMN_EXPIRES_AT: expiresAt,
+	}, goqu.Or(
+		goqu.C(COLUMN_EXPIRES_AT).Lte(now),
+		goqu.C(COLUMN_OWNER).Eq(owner),
+	))
+
+	if err != nil || acquired {
+		return acquired, err
+	}
+
+	return st.leaseInsert(ctx, leaseName, owner, expiresAt)
+}
+
+// RenewLease extends the named lease held by the owner, for the duration
+// of the TTL from now
+//
+// Parameters:
+// - ctx: the context
+// - leaseName: the name of the lease
+// - owner: the owner holding the lease
+// - ttl: how long the lease is held from now
+//
+// Returns:
+// - bool - true if renewed, false if the owner does not hold the lease,
+// or it expired
+// - error - nil if no error, error otherwise
+func (st *store) RenewLease(ctx context.Context, leaseName string, owner string, ttl time.Duration) (bool, error) {
+	if err := st.leaseValidate(leaseName, owner); err != nil {
+		return false, err
+	}
+
+	if ttl <= 0 {
+		return false, errors.New("settingstore > renew lease. ttl must be positive")
+	}
+
+	// an expired lease may be taken by another owner, so it is not renewed
+	return st.leaseUpdate(ctx, leaseName, goqu.Record{
+		COLUMN_EXPIRES_AT: st.leaseExpiresAtExpression(ttl),
+	}, goqu.And(
+		goqu.C(COLUMN_OWNER).Eq(owner),
+		goqu.C(COLUMN_EXPIRES_AT).Gt(st.leaseNowExpression()),
+	))
+}
+
+// ReleaseLease releases the named lease held by the owner, so that
+// another owner may acquire it before it expires
+//
+// Parameters:
+// - ctx: the context
+// - leaseName: the name of the lease
+// - owner: the owner holding the lease
+//
+// Returns:
+// - bool - true if released, false if the owner does not hold the lease
+// - error - nil if no error, error otherwise
+func (st *store) ReleaseLease(ctx context.Context, leaseName string, owner string) (bool, error) {
+	if err := st.leaseValidate(leaseName, owner); err != nil {
+		return false, err
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(st.dbDriverName).
+		Delete(st.leaseTableName).
+		Prepared(true).
+		Where(st.leaseExpression(leaseName)).
+		Where(goqu.C(COLUMN_OWNER).Eq(owner)).
+		ToSQL()
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	st.logSql("delete", sqlStr, params...)
+
+	return st.leaseExecute(ctx, sqlStr, params...)
+}
+
+// leaseValidate returns an error if leases are not enabled,
+// or the name or the owner is empty
+func (store *store) leaseValidate(leaseName string, owner string) error {
+	if store.leaseTableName == "" {
+		return errLeaseNotEnabled
+	}
+
+	if leaseName == "" {
+		return errors.New("settingstore > lease. name cannot be empty")
+	}
+
+	if owner == "" {
+		return errors.New("settingstore > lease. owner cannot be empty")
+	}
+
+	return nil
+}
+
+// leaseExpression returns the condition matching the named lease
+func (store *store) leaseExpression(leaseName string) exp.Expression {
+	return goqu.And(
+		goqu.C(COLUMN_TENANT_ID).Eq(store.tenantID),
+		goqu.C(COLUMN_LEASE_NAME).Eq(store.namespacedKey(leaseName)),
+	)
+}
+
+// leaseUpdate updates the named lease, if it matches the condition
+//
+// The version is incremented, so that the row changes even if the values
+// do not, which MySQL would not count as an affected row otherwise
+func (store *store) leaseUpdate(ctx context.Context, leaseName string, record goqu.Record, condition exp.Expression) (bool, error) {
+	record[COLUMN_UPDATED_AT] = carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+	record[COLUMN_VERSION] = versionIncrementExpression()
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(store.leaseTableName).
+		Prepared(true).
+		Set(record).
+		Where(store.leaseExpression(leaseName)).
+		Where(condition).
+		ToSQL()
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	store.logSql("update", sqlStr, params...)
+
+	return store.leaseExecute(ctx, sqlStr, params...)
+}
+
+// leaseInsert creates the named lease, held by the owner, unless it was
+// created concurrently by another owner
+func (store *store) leaseInsert(ctx context.Context, leaseName string, owner string, expiresAt exp.Expression) (bool, error) {
+	now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+	sqlStr, params, errSql := store.insertIgnoringConflictSql(goqu.Dialect(store.dbDriverName).
+		Insert(store.leaseTableName).
+		Prepared(true).
+		Rows(goqu.Record{
+			COLUMN_ID:         uid.HumanUid(),
+			COLUMN_TENANT_ID:  store.tenantID,
+			COLUMN_LEASE_NAME: store.namespacedKey(leaseName),
+			COLUMN_OWNER:      owner,
+			COLUMN_VERSION:    1,
+			COLUMN_CREATED_AT: now,
+			COLUMN_UPDATED_AT: now,
+			COLUMN_EXPIRES_AT: expiresAt,
+		}))
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	store.logSql("create", sqlStr, params...)
+
+	acquired, err := store.leaseExecute(ctx, sqlStr, params...)
+
+	if err == nil || upsertSupported(store.dbDriverName) {
+		return acquired, err
+	}
+
+	// without native upserts, the insert fails if the lease was created concurrently
+	if exists, errExists := store.leaseExists(ctx, leaseName); errExists == nil && exists {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// leaseExists returns true if the named lease exists, held or expired
+func (store *store) leaseExists(ctx context.Context, leaseName string) (bool, error) {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.leaseTableName).
+		Prepared(true).
+		Select(COLUMN_ID).
+		Where(store.leaseExpression(leaseName)).
+		ToSQL()
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	store.logSql("select", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(rows) > 0, nil
+}
+
+// leaseExecute executes the statement, and returns true if it affected a row
+func (store *store) leaseExecute(ctx context.Context, sqlStr string, params ...any) (bool, error) {
+	result, err := store.executeSql(ctx, sqlStr, params...)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// leaseNowExpression returns the current time of the clock of the database,
+// in UTC and to the second of the datetime columns. On the other dialects
+// it is the current time of the clock of the process
+func (store *store) leaseNowExpression() exp.Expression {
+	switch store.dbDriverName {
+	case sb.DIALECT_MYSQL:
+		return goqu.L("UTC_TIMESTAMP()")
+	case sb.DIALECT_POSTGRES:
+		return goqu.L("DATE_TRUNC('second', CLOCK_TIMESTAMP() AT TIME ZONE 'UTC')")
+	case sb.DIALECT_SQLITE, "sqlite3":
+		return goqu.L("DATETIME('now')")
+	case sb.DIALECT_MSSQL, "sqlserver":
+		return goqu.L("CAST(SYSUTCDATETIME() AS DATETIME2(0))")
+	}
+
+	return goqu.V(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
+}
+
+// leaseExpiresAtExpression returns the expiry of a lease taken now for the
+// TTL, by the clock of the database, with the TTL rounded up to the second
+// and one second added, as the current time is truncated to the second
+func (store *store) leaseExpiresAtExpression(ttl time.Duration) exp.Expression {
+	seconds := int64((ttl+time.Second-1)/time.Second) + 1
+
+	switch store.dbDriverName {
+	case sb.DIALECT_MYSQL:
+		return goqu.L("DATE_ADD(?, INTERVAL ? SECOND)", store.leaseNowExpression(), seconds)
+	case sb.DIALECT_POSTGRES:
+		return goqu.L("? + MAKE_INTERVAL(secs => ?)", store.leaseNowExpression(), seconds)
+	case sb.DIALECT_SQLITE, "sqlite3":
+		return goqu.L("DATETIME('now', ?)", "+"+strconv.FormatInt(seconds, 10)+" seconds")
+	case sb.DIALECT_MSSQL, "sqlserver":
+		return goqu.L("DATEADD(SECOND, ?, ?)", seconds, store.leaseNowExpression())
+	}
+
+	expires := time.Now().UTC().Truncate(time.Second).Add(time.Duration(seconds) * time.Second)
+
+	return goqu.V(carbon.CreateFromStdTime(expires, carbon.UTC).ToDateTimeString(carbon.UTC))
+}
@@ -0,0 +1,230 @@
+package settingstore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gouniverse/sb"
+)
+
+func initLeaseStore(t *testing.T) (*sql.DB, StoreInterface) {
+	// a database file, so that the workers use connections of their own
+	db, err := initDB(filepath.Join(t.TempDir(), "lease.db"))
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		LeaseTableName:     "setting_lease",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	return db, store
+}
+
+func TestStoreAcquireLease(t *testing.T) {
+	db, store := initLeaseStore(t)
+
+	ctx := context.Background()
+
+	acquired, err := store.AcquireLease(ctx, "jobs.invoices", "worker-1", time.Minute)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !acquired {
+		t.Fatal("Free lease MUST be acquired")
+	}
+
+	if acquired, _ := store.AcquireLease(ctx, "jobs.invoices", "worker-2", time.Minute); acquired {
+		t.Fatal("Held lease MUST NOT be acquired by another owner")
+	}
+
+	if renewed, _ := store.RenewLease(ctx, "jobs.invoices", "worker-2", time.Minute); renewed {
+		t.Fatal("Lease MUST NOT be renewed by another owner")
+	}
+
+	if renewed, _ := store.RenewLease(ctx, "jobs.invoices", "worker-1", time.Minute); !renewed {
+		t.Fatal("Lease MUST be renewed by its owner")
+	}
+
+	if acquired, _ := store.AcquireLease(ctx, "jobs.invoices", "worker-1", time.Minute); !acquired {
+		t.Fatal("Lease MUST be acquired again by its owner")
+	}
+
+	// the lease of worker-1 expires
+	if _, err := db.Exec(`UPDATE setting_lease SET expires_at = '2020-01-01 00:00:00'`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if renewed, _ := store.RenewLease(ctx, "jobs.invoices", "worker-1", time.Minute); renewed {
+		t.Fatal("Expired lease MUST NOT be renewed")
+	}
+
+	if acquired, _ := store.AcquireLease(ctx, "jobs.invoices", "worker-2", time.Minute); !acquired {
+		t.Fatal("Expired lease MUST be taken by another owner")
+	}
+
+	if released, _ := store.ReleaseLease(ctx, "jobs.invoices", "worker-1"); released {
+		t.Fatal("Lease MUST NOT be released by another owner")
+	}
+
+	if released, _ := store.ReleaseLease(ctx, "jobs.invoices", "worker-2"); !released {
+		t.Fatal("Lease MUST be released by its owner")
+	}
+
+	if acquired, _ := store.ForTenant("acme").AcquireLease(ctx, "jobs.invoices", "worker-3", time.Minute); !acquired {
+		t.Fatal("Leases of the tenants MUST be apart")
+	}
+
+	if acquired, _ := store.AcquireLease(ctx, "jobs.invoices", "worker-1", time.Minute); !acquired {
+		t.Fatal("Released lease MUST be acquired")
+	}
+}
+
+func TestStoreAcquireLeaseConcurrent(t *testing.T) {
+	_, store := initLeaseStore(t)
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var holders atomic.Int32
+	var acquisitions atomic.Int32
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(owner string) {
+			defer wg.Done()
+
+			for j := 0; j < 20; j++ {
+				acquired, err := store.AcquireLease(ctx, "jobs.cleanup", owner, time.Minute)
+
+				if err != nil {
+					t.Error("unexpected error:", err)
+					return
+				}
+
+				if !acquired {
+					continue
+				}
+
+				acquisitions.Add(1)
+
+				if holders.Add(1) > 1 {
+					t.Error("Lease MUST be held by a single owner at a time")
+				}
+
+				time.Sleep(time.Millisecond) // runs the job
+
+				holders.Add(-1)
+
+				if _, err := store.ReleaseLease(ctx, "jobs.cleanup", owner); err != nil {
+					t.Error("unexpected error:", err)
+					return
+				}
+			}
+		}("worker-" + strconv.Itoa(i))
+	}
+
+	wg.Wait()
+
+	if acquisitions.Load() < 1 {
+		t.Fatal("Lease MUST be acquired")
+	}
+}
+
+func TestStoreLeaseNotEnabled(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	if _, err := store.AcquireLease(context.Background(), "jobs.invoices", "worker-1", time.Minute); err == nil {
+		t.Fatal("Leases MUST fail without a lease table")
+	}
+}
+
+func TestStoreLeaseDatabaseClock(t *testing.T) {
+	db, store := initLeaseStore(t)
+
+	ctx := context.Background()
+
+	if acquired, err := store.AcquireLease(ctx, "jobs.invoices", "worker-1", 90*time.Second); err != nil || !acquired {
+		t.Fatal("Free lease MUST be acquired, found:", acquired, err)
+	}
+
+	held := 0
+
+	// the expiry is the TTL, rounded up and with one second added, from now by the clock of the database
+	err := db.QueryRow(`SELECT COUNT(*) FROM setting_lease
+		WHERE expires_at > DATETIME('now', '+90 seconds') AND expires_at <= DATETIME('now', '+91 seconds')`).Scan(&held)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if held != 1 {
+		t.Fatal("Expiry MUST be set by the clock of the database")
+	}
+
+	// expires in a second by the clock of the database, whatever the clock of the worker
+	if _, err := db.Exec(`UPDATE setting_lease SET expires_at = DATETIME('now', '+1 seconds')`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if acquired, _ := store.AcquireLease(ctx, "jobs.invoices", "worker-2", time.Minute); acquired {
+		t.Fatal("Lease not yet expired by the clock of the database MUST NOT be taken")
+	}
+}
+
+func TestLeaseClockExpressions(t *testing.T) {
+	expected := map[string][]string{
+		sb.DIALECT_MYSQL:    {"DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND)", "`expires_at` <= UTC_TIMESTAMP()"},
+		sb.DIALECT_POSTGRES: {"DATE_TRUNC('second', CLOCK_TIMESTAMP() AT TIME ZONE 'UTC') + MAKE_INTERVAL(secs => $1)", `"expires_at" <= DATE_TRUNC('second', CLOCK_TIMESTAMP() AT TIME ZONE 'UTC')`},
+	}
+
+	for dialect, fragments := range expected {
+		st := &store{dbDriverName: dialect}
+
+		sqlStr, params, err := goqu.Dialect(dialect).
+			Update("setting_lease").
+			Prepared(true).
+			Set(goqu.Record{COLUMN_EXPIRES_AT: st.leaseExpiresAtExpression(90 * time.Second)}).
+			Where(goqu.C(COLUMN_EXPIRES_AT).Lte(st.leaseNowExpression())).
+			ToSQL()
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		for _, fragment := range fragments {
+			if !strings.Contains(sqlStr, fragment) {
+				t.Fatalf("SQL of %s MUST contain %q, found: %s", dialect, fragment, sqlStr)
+			}
+		}
+
+		if len(params) != 1 || params[0] != int64(91) {
+			t.Fatalf("TTL of %s MUST be rounded up with one second added, found: %v", dialect, params)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// migrateColumns adds the columns missing from an existing table
+//
+// Tables created by older versions of the store lack the newer columns.
+// Each missing column is added as nullable (existing rows have no value for
+// it), and then backfilled with its default value, if it has one.
+//
+// Parameters:
+// - ctx: the context
+// - tableName: the name of the table
+// - columns: the columns the table must have
+// - defaults: the values to backfill the added columns with, by column name
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (store *store) migrateColumns(ctx context.Context, tableName string, columns []sb.Column, defaults map[string]string) error {
+	existingColumns, err := store.tableColumnNames(ctx, tableName)
+
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		exists := lo.ContainsBy(existingColumns, func(existingColumn string) bool {
+			return strings.EqualFold(existingColumn, column.Name)
+		})
+
+		if exists {
+			continue
+		}
+
+		column.Nullable = true
+		column.Unique = false // not supported when adding a column to existing rows
+
+		sqlStr, err := sb.NewBuilder(store.dbDriverName).
+			TableColumnAdd(tableName, column)
+
+		if err != nil {
+			return err
+		}
+
+		store.logSql("migrate", sqlStr)
+
+		if _, err := database.Execute(database.Context(ctx, store.db), sqlStr); err != nil {
+			return err
+		}
+
+		defaultValue, hasDefault := defaults[column.Name]
+
+		if !hasDefault {
+			continue
+		}
+
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			Update(tableName).
+			Prepared(true).
+			Set(goqu.Record{column.Name: defaultValue}).
+			Where(goqu.C(column.Name).IsNull()).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("migrate", sqlStr, params...)
+
+		if _, err := database.Execute(database.Context(ctx, store.db), sqlStr, params...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateKeyIndex creates the unique index of the keys, if it does not exist
+//
+// Tables created by older versions of the store may have duplicate
+// settings, saved by concurrent calls for the same key. Before the index
+// is created, the most recently updated of the duplicates is kept, and
+// the others are deleted.
+//
+// Parameters:
+// - ctx: the context
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) migrateKeyIndex(ctx context.Context) error {
+	exists, err := st.indexExists(ctx, st.settingTableName, st.keyIndexName())
+
+	if err != nil || exists {
+		return err
+	}
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		if err := txStore.deduplicateKeys(ctx); err != nil {
+			return err
+		}
+
+		sqlStr := txStore.sqlCreateKeyIndex()
+
+		txStore.logSql("migrate", sqlStr)
+
+		_, err := txStore.executeSql(ctx, sqlStr)
+
+		return err
+	})
+}
+
+// indexExists returns true if the table has the index
+func (store *store) indexExists(ctx context.Context, tableName string, indexName string) (bool, error) {
+	dialect := goqu.Dialect(store.dbDriverName)
+
+	var query *goqu.SelectDataset
+
+	switch store.dbDriverName {
+	case sb.DIALECT_MYSQL:
+		query = dialect.From(goqu.S("information_schema").Table("statistics")).Where(
+			goqu.C("table_schema").Eq(goqu.L("DATABASE()")),
+			goqu.C("table_name").Eq(tableName),
+			goqu.C("index_name").Eq(indexName))
+	case sb.DIALECT_POSTGRES:
+		query = dialect.From("pg_indexes").Where(
+			goqu.C("tablename").Eq(tableName),
+			goqu.C("indexname").Eq(indexName))
+	case sb.DIALECT_MSSQL, "sqlserver":
+		query = dialect.From(goqu.S("sys").Table("indexes")).Where(
+			goqu.C("object_id").Eq(goqu.Func("OBJECT_ID", tableName)),
+			goqu.C("name").Eq(indexName))
+	default:
+		query = dialect.From("sqlite_master").Where(
+			goqu.C("type").Eq("index"),
+			goqu.C("tbl_name").Eq(tableName),
+			goqu.C("name").Eq(indexName))
+	}
+
+	sqlStr, params, errSql := query.
+		Prepared(true).
+		Select(goqu.COUNT(goqu.Star()).As("count")).
+		ToSQL()
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	store.logSql("migrate", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(rows) > 0 && rows[0]["count"] != "0", nil
+}
+
+// deduplicateKeys deletes the settings which would violate the unique
+// index of the keys, keeping the most recently updated of each key
+func (store *store) deduplicateKeys(ctx context.Context) error {
+	isMySQL := store.dbDriverName == sb.DIALECT_MYSQL
+
+	groupColumns := []any{goqu.C(COLUMN_TENANT_ID), goqu.C(COLUMN_SETTING_KEY)}
+
+	if isMySQL {
+		groupColumns = append(groupColumns, goqu.C(COLUMN_SOFT_DELETED_AT))
+	}
+
+	liveCondition := lo.Ternary[exp.Expression](isMySQL, goqu.And(), goqu.L(liveKeyPredicate))
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.settingTableName).
+		Prepared(true).
+		Select(groupColumns...).
+		Where(liveCondition).
+		GroupBy(groupColumns...).
+		Having(goqu.COUNT(goqu.Star()).Gt(1)).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("migrate", sqlStr, params...)
+
+	groups, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+
+	for _, group := range groups {
+		conditions := []exp.Expression{
+			goqu.C(COLUMN_TENANT_ID).Eq(group[COLUMN_TENANT_ID]),
+			goqu.C(COLUMN_SETTING_KEY).Eq(group[COLUMN_SETTING_KEY]),
+			liveCondition,
+		}
+
+		if isMySQL {
+			// read back in the format of the driver
+			softDeletedAt := carbon.Parse(group[COLUMN_SOFT_DELETED_AT], carbon.UTC).ToDateTimeString(carbon.UTC)
+			conditions = append(conditions, goqu.C(COLUMN_SOFT_DELETED_AT).Eq(softDeletedAt))
+		}
+
+		sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+			From(store.settingTableName).
+			Prepared(true).
+			Select(COLUMN_ID).
+			Where(conditions...).
+			Order(goqu.C(COLUMN_UPDATED_AT).Desc(), goqu.C(COLUMN_ID).Asc()).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("migrate", sqlStr, params...)
+
+		rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+		if err != nil {
+			return err
+		}
+
+		if len(rows) < 2 {
+			continue
+		}
+
+		duplicateIDs := lo.Map(rows[1:], func(row map[string]string, _ int) string {
+			return row[COLUMN_ID]
+		})
+
+		sqlStr, params, errSql = goqu.Dialect(store.dbDriverName).
+			Delete(store.settingTableName).
+			Prepared(true).
+			Where(goqu.C(COLUMN_ID).In(duplicateIDs)).
+			ToSQL()
+
+		if errSql != nil {
+			return errSql
+		}
+
+		store.logSql("migrate", sqlStr, params...)
+
+		if _, err := store.executeSql(ctx, sqlStr, params...); err != nil {
+			return err
+		}
+
+		deleted += len(duplicateIDs)
+	}
+
+	if deleted > 0 {
+		store.sqlLogger.Info("settingstore: deleted duplicate settings", slog.Int("count", deleted))
+	}
+
+	return nil
+}
+
+// tableColumnNames returns the names of the columns of a table
+//
+// It selects all the columns of an empty result set, which works
+// the same way on every supported dialect.
+//
+// Parameters:
+// - ctx: the context
+// - tableName: the name of the table
+//
+// Returns:
+// - []string - the names of the columns
+// - error - nil if no error, error otherwise
+func (store *store) tableColumnNames(ctx context.Context, tableName string) ([]string, error) {
+	if store.db == nil {
+		return nil, errors.New("setting store: database is nil")
+	}
+
+	sqlStr, _, errSql := goqu.Dialect(store.dbDriverName).
+		From(tableName).
+		Select(goqu.Star()).
+		Where(goqu.L("1 = 0")).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	rows, err := database.Query(database.Context(ctx, store.db), sqlStr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return rows.Columns()
+}
@@ -0,0 +1,62 @@
+package settingstore
+
+import (
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// Namespace returns a view of the store scoped to the namespace
+//
+// Every key is transparently prefixed with the namespace and a dot on
+// write, and stripped on read. So Namespace("billing").Set(ctx, "currency", "EUR")
+// saves the key "billing.currency". SettingList, SettingCount and the
+// other methods of the view never see the settings outside the namespace,
+// so the view is safe to hand to a third-party module.
+//
+// The views nest, Namespace("billing").Namespace("stripe") is scoped to
+// "billing.stripe.". They share the database, the cache and the revision
+// counter of the store. PurgeExpired and PurgeSoftDeleted on a view only
+// purge the namespace. Close on a view does nothing, as the background
+// sweeper belongs to the store.
+//
+// Parameters:
+// - namespace: the namespace, an empty namespace returns the store itself
+//
+// Returns:
+// - StoreInterface - the namespaced view of the store
+func (store *store) Namespace(namespace string) StoreInterface {
+	namespace = strings.TrimSuffix(namespace, ".")
+
+	if namespace == "" {
+		return store
+	}
+
+	view := *store
+	view.keyPrefix = store.keyPrefix + namespace + "."
+	view.sweeper = nil
+
+	return &view
+}
+
+// namespacedKey returns the key as saved in the database
+func (store *store) namespacedKey(settingKey string) string {
+	return store.keyPrefix + settingKey
+}
+
+// namespaceStrip returns the key as seen through the namespace
+func (store *store) namespaceStrip(settingKey string) string {
+	return strings.TrimPrefix(settingKey, store.keyPrefix)
+}
+
+// namespaceExpression returns the condition limiting the rows to the
+// namespace of the store, an empty condition (ignored by Where) if the
+// store is not namespaced
+func (store *store) namespaceExpression() exp.Expression {
+	if store.keyPrefix == "" {
+		return goqu.And()
+	}
+
+	return keyPrefixExpression(store.keyPrefix)
+}
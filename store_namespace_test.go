@@ -0,0 +1,159 @@
+package settingstore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStoreNamespace(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	billing := store.Namespace("billing")
+
+	if err := billing.Set(ctx, "currency", "EUR"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "currency", "USD"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.Get(ctx, "billing.currency", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "EUR" {
+		t.Fatal("The key MUST be saved with the namespace prefix, found:", value)
+	}
+
+	value, err = billing.Get(ctx, "currency", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "EUR" {
+		t.Fatal("The view MUST read its own key, found:", value)
+	}
+
+	settings, err := billing.SettingList(ctx, SettingQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(settings) != 1 || settings[0].GetKey() != "currency" {
+		t.Fatal("SettingList MUST be limited to the namespace, with the prefix stripped, found:", len(settings))
+	}
+
+	count, err := billing.SettingCount(ctx, SettingQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("SettingCount MUST be limited to the namespace, found:", count)
+	}
+
+	outside, err := store.SettingFindByKey(ctx, "currency")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	found, err := billing.SettingFindByID(ctx, outside.GetID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found != nil {
+		t.Fatal("The view MUST NOT find a setting outside the namespace")
+	}
+
+	if err := billing.SettingDeleteByID(ctx, outside.GetID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if has, _ := store.Has(ctx, "currency"); !has {
+		t.Fatal("The view MUST NOT delete a setting outside the namespace")
+	}
+
+	setting, err := billing.SettingFindByKey(ctx, "currency")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	setting.SetValue("GBP")
+
+	if err := billing.SettingUpdate(ctx, setting); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value, _ := store.Get(ctx, "billing.currency", ""); value != "GBP" {
+		t.Fatal("SettingUpdate MUST update the namespaced key, found:", value)
+	}
+}
+
+func TestStoreNamespaceNested(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	billing := store.Namespace("billing")
+	stripe := billing.Namespace("stripe")
+
+	if err := stripe.Set(ctx, "key", "sk_test"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := billing.Set(ctx, "currency", "EUR"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value, _ := store.Get(ctx, "billing.stripe.key", ""); value != "sk_test" {
+		t.Fatal("The nested namespaces MUST be joined, found:", value)
+	}
+
+	if value, _ := billing.Get(ctx, "stripe.key", ""); value != "sk_test" {
+		t.Fatal("The parent view MUST see the nested key, found:", value)
+	}
+
+	settings, err := billing.SettingList(ctx, SettingQuery().SetKeyPrefix("stripe."))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(settings) != 1 || settings[0].GetKey() != "stripe.key" {
+		t.Fatal("The key prefix MUST be relative to the namespace, found:", len(settings))
+	}
+
+	deleted, err := stripe.DeleteByPrefix(ctx, "k")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if deleted != 1 {
+		t.Fatal("DeleteByPrefix MUST delete within the namespace, found:", deleted)
+	}
+
+	if value, _ := billing.Get(ctx, "currency", ""); value != "EUR" {
+		t.Fatal("The setting outside the nested namespace MUST be kept, found:", value)
+	}
+}
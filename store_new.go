@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
 )
 
 // NewStoreOptions define the options for creating a new setting store
@@ -17,6 +20,89 @@ type NewStoreOptions struct {
 	AutomigrateEnabled bool
 	DebugEnabled       bool
 	SqlLogger          *slog.Logger
+
+	// ExpirySweepInterval, if set, starts a background worker which
+	// purges the expired settings at this interval. Stop it with Close
+	ExpirySweepInterval time.Duration
+
+	// SoftDeletedRetention is how long the background worker keeps
+	// soft deleted settings before purging them. Zero keeps them forever
+	SoftDeletedRetention time.Duration
+
+	// CacheEnabled enables the in-process read-through cache used by
+	// Get, GetAny and GetMap. Writes through this store invalidate it
+	CacheEnabled bool
+
+	// CacheMaxEntries is the maximum number of cached keys, the least
+	// recently used are evicted first. Defaults to 1000
+	CacheMaxEntries int
+
+	// CacheTTL is how long a value is cached. Defaults to 1 minute
+	CacheTTL time.Duration
+
+	// CacheMissingKeys enables caching that a key is not set
+	CacheMissingKeys bool
+
+	// RevisionTableName, if set, enables the store-wide revision counter,
+	// which every write bumps in the same transaction. Caching stores poll
+	// it to drop the values changed by other processes. All the processes
+	// sharing the settings table must set it
+	RevisionTableName string
+
+	// CacheRevisionCheckInterval is how often a caching store polls the
+	// revision counter, which bounds the staleness. Defaults to 1 second
+	CacheRevisionCheckInterval time.Duration
+
+	// HistoryTableName, if set, enables the history of the changes, which
+	// records the old and the new value on every create, update, soft delete
+	// and delete, in the same transaction. AutoMigrate creates the table
+	HistoryTableName string
+
+	// AuditTableName, if set, enables the audit trail, which records every
+	// change with the actor, the reason and the request ID attached to the
+	// context with WithActor, WithReason and WithRequestID, in the same
+	// transaction. AutoMigrate creates the table
+	AuditTableName string
+
+	// AuditHMACKey, if set, signs the hash chain of the audit trail with
+	// HMAC-SHA256, so that it cannot be recomputed without the key after
+	// the entries are edited. Otherwise the entries are chained with SHA-256
+	AuditHMACKey []byte
+
+	// LeaseTableName, if set, enables the leases, which let one owner at
+	// a time hold a named lease until it expires, i.e. to run a job on a
+	// single worker. AutoMigrate creates the table
+	LeaseTableName string
+
+	// EncryptionKeys, if set, enables the encryption at rest of the secret
+	// settings with AES-256-GCM. It is the keyring of the 32 byte keys by
+	// key ID, which must keep the old keys until ReencryptAll resealed the
+	// values sealed with them
+	EncryptionKeys map[string][]byte
+
+	// EncryptionKeyID is the ID of the key in EncryptionKeys sealing the
+	// new values, required with EncryptionKeys
+	EncryptionKeyID string
+
+	// SecretKeys are the full keys, with their namespace, of the settings
+	// encrypted at rest. Their values are encrypted on write, decrypted on
+	// read, and recorded encrypted in the history and the audit trail
+	SecretKeys []string
+
+	// SecretKeyPrefixes are the prefixes of the full keys of the settings
+	// encrypted at rest, i.e. "smtp." or "api.tokens."
+	SecretKeyPrefixes []string
+
+	// SensitiveKeys are the full keys, with their namespace, of the settings
+	// whose values are masked in the SQL log, in the settings, the history
+	// and the audit entries listed, and in the log output of the settings.
+	// Get still returns them, Reveal returns them with an audit entry. The
+	// secret keys are sensitive too
+	SensitiveKeys []string
+
+	// SensitiveKeyPrefixes are the prefixes of the full keys of the
+	// sensitive settings, i.e. "oauth."
+	SensitiveKeyPrefixes []string
 }
 
 // NewStore creates a new setting store
@@ -28,6 +114,13 @@ func NewStore(opts NewStoreOptions) (*store, error) {
 		dbDriverName:       opts.DbDriverName,
 		debugEnabled:       opts.DebugEnabled,
 		sqlLogger:          opts.SqlLogger,
+		revisionTableName:  opts.RevisionTableName,
+		historyTableName:   opts.HistoryTableName,
+		auditTableName:     opts.AuditTableName,
+		auditHMACKey:       opts.AuditHMACKey,
+		leaseTableName:     opts.LeaseTableName,
+		sensitiveKeys:      newKeyMatcher(opts.SensitiveKeys, opts.SensitiveKeyPrefixes),
+		flights:            &singleflight.Group{},
 	}
 
 	if store.settingTableName == "" {
@@ -42,13 +135,41 @@ func NewStore(opts NewStoreOptions) (*store, error) {
 		store.dbDriverName = sb.DatabaseDriverName(store.db)
 	}
 
+	if len(opts.EncryptionKeys) > 0 {
+		keyring, err := newEncryptionKeyring(opts.EncryptionKeys, opts.EncryptionKeyID, opts.SecretKeys, opts.SecretKeyPrefixes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		store.encryption = keyring
+	} else if len(opts.SecretKeys) > 0 || len(opts.SecretKeyPrefixes) > 0 {
+		return nil, errors.New("setting store: EncryptionKeys are required with SecretKeys and SecretKeyPrefixes")
+	}
+
 	if store.sqlLogger == nil {
 		store.sqlLogger = slog.Default()
 	}
 
+	if opts.CacheEnabled {
+		maxEntries := lo.Ternary(opts.CacheMaxEntries > 0, opts.CacheMaxEntries, 1000)
+		ttl := lo.Ternary(opts.CacheTTL > 0, opts.CacheTTL, time.Minute)
+		store.cache = newSettingCache(maxEntries, ttl, opts.CacheMissingKeys)
+	}
+
+	if store.cache != nil && store.revisionTableName != "" {
+		store.revisions = &revisionTracker{
+			checkInterval: lo.Ternary(opts.CacheRevisionCheckInterval > 0, opts.CacheRevisionCheckInterval, time.Second),
+		}
+	}
+
 	if store.automigrateEnabled {
 		store.AutoMigrate(context.Background())
 	}
 
+	if opts.ExpirySweepInterval > 0 {
+		store.startSweeper(opts.ExpirySweepInterval, opts.SoftDeletedRetention)
+	}
+
 	return store, nil
 }
@@ -0,0 +1,153 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/samber/lo"
+)
+
+// Reveal returns the unmasked value of a setting, and records in the audit
+// trail that it was revealed, if it is sensitive, with the actor, the reason
+// and the request ID attached to the context
+//
+// The values of the sensitive settings are masked in the SQL log, and in
+// the settings, the history and the audit entries listed, i.e. by admin
+// tooling. Get still returns them to the application
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+//
+// Returns:
+// - string - the value of the setting
+// - bool - true if the setting was found, false otherwise
+// - error - nil if no error, error otherwise
+func (st *store) Reveal(ctx context.Context, settingKey string) (string, bool, error) {
+	if settingKey == "" {
+		return "", false, errors.New("settingstore > reveal. key cannot be empty")
+	}
+
+	value := ""
+	found := false
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		setting, err := txStore.settingFindByKey(ctx, settingKey)
+
+		if err != nil || setting == nil {
+			return err
+		}
+
+		value, found = setting.GetValue(), true
+
+		if !setting.IsSensitive() {
+			return nil
+		}
+
+		// the value itself is not recorded
+		return txStore.auditRecord(ctx, recordedChange{
+			settingID: setting.GetID(),
+			tenantID:  txStore.tenantID,
+			key:       txStore.namespacedKey(settingKey),
+			action:    AUDIT_ACTION_REVEAL,
+		})
+	})
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, found, nil
+}
+
+// revealList lists the settings matching the query, with the values of the
+// sensitive settings unmasked, and records in the audit trail that they
+// were revealed, i.e. when they are bound to a struct
+func (store *store) revealList(ctx context.Context, query SettingQueryInterface) ([]SettingInterface, error) {
+	settings, err := store.settingList(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	revealed := lo.FilterMap(settings, func(setting SettingInterface, _ int) (recordedChange, bool) {
+		return recordedChange{
+			settingID: setting.GetID(),
+			tenantID:  store.tenantID,
+			key:       store.namespacedKey(setting.GetKey()),
+			action:    AUDIT_ACTION_REVEAL,
+		}, setting.IsSensitive() && setting.GetValue() != ""
+	})
+
+	if len(revealed) < 1 {
+		return settings, nil
+	}
+
+	if err := store.auditRecord(ctx, revealed...); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// isSensitiveKey returns true if the value of the full key, with its
+// namespace, is masked. The secret keys are sensitive too
+func (store *store) isSensitiveKey(fullKey string) bool {
+	return store.sensitiveKeys.matches(fullKey) || store.isSecretKey(fullKey)
+}
+
+// isSensitiveSettingKey returns true if the value of the key,
+// relative to the namespace of the store, is masked
+func (store *store) isSensitiveSettingKey(settingKey string) bool {
+	return store.isSensitiveKey(store.namespacedKey(settingKey))
+}
+
+// hasSensitiveKeys returns true if any key is sensitive
+func (store *store) hasSensitiveKeys() bool {
+	return !store.sensitiveKeys.isEmpty() || (store.encryption != nil && !store.encryption.secrets.isEmpty())
+}
+
+// redactParams returns the parameters of a statement for the SQL log,
+// with the values masked if the full key is sensitive
+func (store *store) redactParams(params []any, fullKey string, values ...string) []any {
+	if !store.debugEnabled || !store.isSensitiveKey(fullKey) {
+		return params
+	}
+
+	redacted := make([]any, len(params))
+
+	for i, param := range params {
+		redacted[i] = param
+
+		for _, value := range values {
+			if text, isText := param.(string); isText && value != "" && text == value {
+				redacted[i] = REDACTED_VALUE
+			}
+		}
+	}
+
+	return redacted
+}
+
+// redactSetting masks the value of the setting, if it is sensitive
+func redactSetting(setting SettingInterface) SettingInterface {
+	if !setting.IsSensitive() || setting.GetValue() == "" {
+		return setting
+	}
+
+	setting.SetValue(REDACTED_VALUE)
+	setting.MarkAsNotDirty() // masked, not changed
+
+	return setting
+}
+
+// redactRow masks the values of the columns of the row, which are not empty
+func redactRow(row map[string]string, columns ...string) map[string]string {
+	for _, column := range columns {
+		if row[column] != "" {
+			row[column] = REDACTED_VALUE
+		}
+	}
+
+	return row
+}
@@ -0,0 +1,295 @@
+package settingstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+func initSensitiveStore(t *testing.T, logOutput *bytes.Buffer) StoreInterface {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                   db,
+		SettingTableName:     "setting",
+		HistoryTableName:     "setting_history",
+		AuditTableName:       "setting_audit",
+		AutomigrateEnabled:   true,
+		DebugEnabled:         true,
+		SqlLogger:            slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		SensitiveKeys:        []string{"stripe.api_key"},
+		SensitiveKeyPrefixes: []string{"oauth."},
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	return store
+}
+
+func TestStoreSensitiveSettingsMasked(t *testing.T) {
+	logOutput := &bytes.Buffer{}
+	store := initSensitiveStore(t, logOutput)
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "stripe.api_key", "sk_live_1"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetMany(ctx, map[string]string{"oauth.secret": "s3cr3t", "site.name": "Acme"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "stripe.api_key", "sk_live_2"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, secret := range []string{"sk_live_1", "sk_live_2", "s3cr3t"} {
+		if strings.Contains(logOutput.String(), secret) {
+			t.Fatalf("SQL log MUST NOT contain the sensitive value %q", secret)
+		}
+	}
+
+	if !strings.Contains(logOutput.String(), "Acme") {
+		t.Fatal("SQL log MUST contain the other values")
+	}
+
+	if value, err := store.Get(ctx, "stripe.api_key", ""); err != nil || value != "sk_live_2" {
+		t.Fatal("Get MUST return the sensitive value, found:", value, err)
+	}
+
+	settings, err := store.SettingList(ctx, SettingQuery())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for _, setting := range settings {
+		expected := map[string]string{"stripe.api_key": REDACTED_VALUE, "oauth.secret": REDACTED_VALUE, "site.name": "Acme"}[setting.GetKey()]
+
+		if setting.GetValue() != expected {
+			t.Fatalf("Setting %q MUST be listed with the value %q, found: %q", setting.GetKey(), expected, setting.GetValue())
+		}
+	}
+
+	setting, err := store.SettingFindByKey(ctx, "oauth.secret")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if setting == nil || setting.GetValue() != REDACTED_VALUE || !setting.IsSensitive() {
+		t.Fatal("Found setting MUST be masked, found:", setting)
+	}
+
+	if err := store.SettingUpdate(ctx, setting.SetKey("oauth.moved")); err == nil {
+		t.Fatal("Key of a masked setting MUST NOT be changed")
+	}
+
+	entries, err := store.SettingHistory(ctx, "stripe.api_key", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetOldValue() != REDACTED_VALUE || entries[0].GetNewValue() != REDACTED_VALUE {
+		t.Fatal("History MUST be masked, found:", entries)
+	}
+
+	auditEntries, err := store.SettingAudit(ctx, SettingAuditQuery().SetKeyPrefix("stripe.api_key"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(auditEntries) != 2 || auditEntries[0].GetNewValue() != REDACTED_VALUE {
+		t.Fatal("Audit trail MUST be masked, found:", auditEntries)
+	}
+}
+
+func TestSettingLogValue(t *testing.T) {
+	logOutput := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(logOutput, nil))
+
+	setting := NewSetting().SetKey("stripe.api_key").SetValue("sk_live_1")
+
+	logger.Info("setting", "setting", setting.SetSensitive(true))
+
+	if strings.Contains(logOutput.String(), "sk_live_1") || !strings.Contains(logOutput.String(), REDACTED_VALUE) {
+		t.Fatal("Sensitive setting MUST be logged masked, found:", logOutput.String())
+	}
+
+	logger.Info("setting", "setting", setting.SetSensitive(false))
+
+	if !strings.Contains(logOutput.String(), "sk_live_1") {
+		t.Fatal("Other settings MUST be logged with the value, found:", logOutput.String())
+	}
+}
+
+func TestStoreReveal(t *testing.T) {
+	store := initSensitiveStore(t, &bytes.Buffer{})
+
+	ctx := WithActor(context.Background(), "admin")
+
+	if err := store.SetMany(ctx, map[string]string{"stripe.api_key": "sk_live_1", "site.name": "Acme"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, found, err := store.Reveal(ctx, "stripe.api_key")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !found || value != "sk_live_1" {
+		t.Fatal("Reveal MUST return the sensitive value, found:", value, found)
+	}
+
+	if value, found, err := store.Reveal(ctx, "site.name"); err != nil || !found || value != "Acme" {
+		t.Fatal("Reveal MUST return the other values, found:", value, found, err)
+	}
+
+	if _, found, err := store.Reveal(ctx, "missing"); err != nil || found {
+		t.Fatal("Missing setting MUST NOT be found, found:", found, err)
+	}
+
+	entries, err := store.SettingAudit(ctx, SettingAuditQuery().SetActor("admin"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	entries = lo.Filter(entries, func(entry SettingAuditEntryInterface, _ int) bool {
+		return entry.GetAction() == AUDIT_ACTION_REVEAL
+	})
+
+	if len(entries) != 1 || entries[0].GetKey() != "stripe.api_key" {
+		t.Fatal("Reveal of the sensitive setting MUST be audited, found:", entries)
+	}
+
+	if err := store.VerifyAuditChain(ctx); err != nil {
+		t.Fatal("Audit chain MUST be intact, found:", err)
+	}
+}
+
+func TestStoreSensitiveSettingsInBulk(t *testing.T) {
+	store := initSensitiveStore(t, &bytes.Buffer{})
+
+	ctx := WithActor(context.Background(), "worker")
+
+	if err := store.SetMany(ctx, map[string]string{"stripe.api_key": "sk_live_1", "oauth.secret": "s3cr3t", "site.name": "Acme"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	resolver, err := NewResolver(ResolverScope{Name: "global", Store: store})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	resolved, err := resolver.ResolveAll(ctx, "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if resolved["stripe.api_key"].Value != REDACTED_VALUE || !resolved["stripe.api_key"].Sensitive {
+		t.Fatal("Resolved sensitive setting MUST be masked, found:", resolved["stripe.api_key"])
+	}
+
+	if resolved["site.name"].Value != "Acme" || resolved["site.name"].Sensitive {
+		t.Fatal("Other resolved settings MUST NOT be masked, found:", resolved["site.name"])
+	}
+
+	config := struct {
+		APIKey   string `setting:"stripe.api_key"`
+		SiteName string `setting:"site.name"`
+	}{}
+
+	if err := Bind(ctx, store, &config); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if config.APIKey != "sk_live_1" || config.SiteName != "Acme" {
+		t.Fatal("Sensitive settings MUST be bound unmasked, found:", config)
+	}
+
+	entries, err := store.SettingAudit(ctx, SettingAuditQuery().SetActor("worker"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	revealed := lo.FilterMap(entries, func(entry SettingAuditEntryInterface, _ int) (string, bool) {
+		return entry.GetKey(), entry.GetAction() == AUDIT_ACTION_REVEAL
+	})
+
+	if len(revealed) != 1 || revealed[0] != "stripe.api_key" {
+		t.Fatal("Bound sensitive settings MUST be audited as revealed, found:", revealed)
+	}
+}
+
+func TestStoreSensitiveParseErrorMasked(t *testing.T) {
+	store := initSensitiveStore(t, &bytes.Buffer{})
+
+	ctx := context.Background()
+
+	if err := store.SetMany(ctx, map[string]string{"stripe.api_key": "hunter2", "oauth.ttl": "hunter2", "site.port": "abc"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	config := struct {
+		APIKey int           `setting:"stripe.api_key"`
+		TTL    time.Duration `setting:"oauth.ttl"`
+	}{}
+
+	errs := map[string]error{}
+	_, errs["GetInt"] = store.GetInt(ctx, "stripe.api_key", 0)
+	_, errs["GetDuration"] = store.GetDuration(ctx, "oauth.ttl", 0)
+	_, errs["GetAs"] = GetAs(ctx, store, "stripe.api_key", 0)
+	_, errs["Increment"] = store.Increment(ctx, "stripe.api_key", 1)
+	errs["Bind"] = Bind(ctx, store, &config)
+
+	for name, err := range errs {
+		var parseError *ParseError
+
+		if !errors.As(err, &parseError) {
+			t.Fatal(name, "MUST return a parse error, found:", err)
+		}
+
+		if parseError.Value != REDACTED_VALUE {
+			t.Fatal(name, "MUST mask the value of a sensitive setting, found:", parseError.Value)
+		}
+
+		if strings.Contains(err.Error(), "hunter2") {
+			t.Fatal(name, "MUST NOT return the value of a sensitive setting in the error, found:", err)
+		}
+	}
+
+	if !errors.Is(errs["GetInt"], strconv.ErrSyntax) {
+		t.Fatal("The kind of the parse error MUST be kept, found:", errs["GetInt"])
+	}
+
+	_, err := store.GetInt(ctx, "site.port", 0)
+
+	var parseError *ParseError
+
+	if !errors.As(err, &parseError) || parseError.Value != "abc" {
+		t.Fatal("Parse error of other settings MUST NOT be masked, found:", err)
+	}
+}
@@ -0,0 +1,224 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+)
+
+var errRevisionRowMissing = errors.New("setting store: revision row is missing, run AutoMigrate")
+
+// revisionTracker keeps the last seen store-wide revision, so that a
+// caching store can detect the writes made by other processes
+type revisionTracker struct {
+	mutex         sync.Mutex
+	checkInterval time.Duration
+	checkedAt     time.Time
+	revision      int64
+	known         bool
+}
+
+// SQLCreateRevisionTable returns a SQL string for creating the revision table
+func (store *store) SQLCreateRevisionTable() string {
+	sql := sb.NewBuilder(store.dbDriverName).
+		Table(store.revisionTableName).
+		Column(sb.Column{
+			Name:       COLUMN_ID,
+			Type:       sb.COLUMN_TYPE_STRING,
+			Length:     255,
+			PrimaryKey: true,
+		}).
+		Column(sb.Column{
+			Name: COLUMN_REVISION,
+			Type: sb.COLUMN_TYPE_INTEGER,
+		}).
+		Column(sb.Column{
+			Name: COLUMN_UPDATED_AT,
+			Type: sb.COLUMN_TYPE_DATETIME,
+		}).
+		CreateIfNotExists()
+
+	return sql
+}
+
+// migrateRevisionTable creates the revision table, and the revision
+// row of the settings table, if they do not exist
+func (store *store) migrateRevisionTable(ctx context.Context) error {
+	sqlStr := store.SQLCreateRevisionTable()
+
+	if sqlStr == "" {
+		return errors.New("setting store: revision table create sql is empty")
+	}
+
+	if _, err := database.Execute(database.Context(ctx, store.db), sqlStr); err != nil {
+		return err
+	}
+
+	_, err := store.revisionCurrent(ctx)
+
+	if err == nil {
+		return nil // the revision row exists
+	}
+
+	if !errors.Is(err, errRevisionRowMissing) {
+		return err
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Insert(store.revisionTableName).
+		Prepared(true).
+		Rows(goqu.Record{
+			COLUMN_ID:         store.settingTableName,
+			COLUMN_REVISION:   0,
+			COLUMN_UPDATED_AT: carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+		}).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("migrate", sqlStr, params...)
+
+	if _, err := database.Execute(database.Context(ctx, store.db), sqlStr, params...); err != nil {
+		// another process may have inserted the row in the meantime
+		if _, errCurrent := store.revisionCurrent(ctx); errCurrent == nil {
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// revisionCurrent returns the current store-wide revision
+func (store *store) revisionCurrent(ctx context.Context) (int64, error) {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.revisionTableName).
+		Prepared(true).
+		Select(COLUMN_REVISION).
+		Where(goqu.C(COLUMN_ID).Eq(store.settingTableName)).
+		Limit(1).
+		ToSQL()
+
+	if errSql != nil {
+		return -1, errSql
+	}
+
+	store.logSql("revision", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return -1, err
+	}
+
+	if len(rows) < 1 {
+		return -1, errRevisionRowMissing
+	}
+
+	return strconv.ParseInt(rows[0][COLUMN_REVISION], 10, 64)
+}
+
+// revisionBump increments the store-wide revision, in the same transaction
+// as the write which calls it. It does nothing if revisions are not enabled
+func (store *store) revisionBump(ctx context.Context) error {
+	if store.revisionTableName == "" {
+		return nil
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(store.revisionTableName).
+		Prepared(true).
+		Set(goqu.Record{
+			COLUMN_REVISION:   goqu.L("? + 1", goqu.C(COLUMN_REVISION)),
+			COLUMN_UPDATED_AT: carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC),
+		}).
+		Where(goqu.C(COLUMN_ID).Eq(store.settingTableName)).
+		ToSQL()
+
+	if errSql != nil {
+		return errSql
+	}
+
+	store.logSql("revision", sqlStr, params...)
+
+	result, err := store.executeSql(ctx, sqlStr, params...)
+
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected < 1 {
+		return errRevisionRowMissing
+	}
+
+	if store.revisions == nil {
+		return nil
+	}
+
+	revision, err := store.revisionCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// our own write must not flush the whole cache on the next check
+	store.afterCommit(func() {
+		store.revisions.observeOwn(revision)
+	})
+
+	return nil
+}
+
+// cacheCheckRevision flushes the cache if another process changed the
+// settings since the last check. The check runs at most once per interval
+func (store *store) cacheCheckRevision(ctx context.Context) error {
+	if store.cache == nil || store.revisions == nil {
+		return nil
+	}
+
+	tracker := store.revisions
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.known && time.Since(tracker.checkedAt) < tracker.checkInterval {
+		return nil
+	}
+
+	revision, err := store.revisionCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if !tracker.known || revision != tracker.revision {
+		store.cache.invalidateAll()
+	}
+
+	tracker.revision = revision
+	tracker.known = true
+	tracker.checkedAt = time.Now()
+
+	return nil
+}
+
+// observeOwn records the revision created by a write of this process,
+// provided no other process wrote in between
+func (tracker *revisionTracker) observeOwn(revision int64) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.known && tracker.revision == revision-1 {
+		tracker.revision = revision
+	}
+}
@@ -0,0 +1,244 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+	"github.com/samber/lo"
+)
+
+// Rollback restores a setting to its state at a version or a time, from the history
+//
+// A setting deleted since is created again, and a setting which did not
+// exist yet is soft deleted. The rollback runs in one transaction and is
+// recorded in the history as new changes, the older changes stay untouched.
+// The history does not keep the expiry, the restored settings never expire
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - versionOrTime: the version of a recorded change (int or int64), or a time.Time
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) Rollback(ctx context.Context, settingKey string, versionOrTime any) error {
+	if st.historyTableName == "" {
+		return errHistoryNotEnabled
+	}
+
+	if settingKey == "" {
+		return errors.New("settingstore > rollback. key cannot be empty")
+	}
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		var target SettingHistoryEntryInterface
+
+		switch versionOrTime := versionOrTime.(type) {
+		case time.Time:
+			states, err := txStore.historyStateAt(ctx, goqu.C(COLUMN_SETTING_KEY).Eq(txStore.namespacedKey(settingKey)), versionOrTime)
+
+			if err != nil {
+				return err
+			}
+
+			target = states[settingKey]
+		case int:
+			entry, err := txStore.historyFindByVersion(ctx, settingKey, int64(versionOrTime))
+
+			if err != nil {
+				return err
+			}
+
+			target = entry
+		case int64:
+			entry, err := txStore.historyFindByVersion(ctx, settingKey, versionOrTime)
+
+			if err != nil {
+				return err
+			}
+
+			target = entry
+		default:
+			return errors.New("settingstore > rollback. versionOrTime must be a version (int64) or a time.Time")
+		}
+
+		_, err := txStore.rollbackKey(ctx, settingKey, target)
+
+		return err
+	})
+}
+
+// RollbackPrefix restores the settings with keys starting with the prefix
+// to their state at a time, from the history
+//
+// Values are restored, settings deleted since are created again, and settings
+// created since are soft deleted. The rollback runs in one transaction and is
+// recorded in the history as new changes, the older changes stay untouched
+//
+// Parameters:
+// - ctx: the context
+// - keyPrefix: the prefix of the keys, i.e. "mail.smtp."
+// - at: the time to restore the settings to
+//
+// Returns:
+// - int64 - the number of settings changed
+// - error - nil if no error, error otherwise
+func (st *store) RollbackPrefix(ctx context.Context, keyPrefix string, at time.Time) (int64, error) {
+	if st.historyTableName == "" {
+		return 0, errHistoryNotEnabled
+	}
+
+	if keyPrefix == "" {
+		return 0, errors.New("settingstore > rollback prefix. key prefix cannot be empty")
+	}
+
+	changed := int64(0)
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		states, err := txStore.historyStateAt(ctx, keyPrefixExpression(txStore.namespacedKey(keyPrefix)), at)
+
+		if err != nil {
+			return err
+		}
+
+		current, err := txStore.settingList(ctx, SettingQuery().
+			SetKeyPrefix(keyPrefix).
+			SetExpiredIncluded(true).
+			SetColumns([]string{COLUMN_SETTING_KEY}))
+
+		if err != nil {
+			return err
+		}
+
+		// the keys existing now, and the keys with a change recorded up to the time
+		keys := lo.Uniq(append(lo.Keys(states), lo.Map(current, func(setting SettingInterface, _ int) string {
+			return setting.GetKey()
+		})...))
+
+		for _, key := range keys {
+			keyChanged, err := txStore.rollbackKey(ctx, key, states[key])
+
+			if err != nil {
+				return err
+			}
+
+			if keyChanged {
+				changed++
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// rollbackKey brings a setting to the state after the recorded change,
+// or removes it if the change is nil or a deletion. Returns true if the
+// setting was changed
+func (store *store) rollbackKey(ctx context.Context, settingKey string, target SettingHistoryEntryInterface) (bool, error) {
+	list, err := store.settingList(ctx, SettingQuery().
+		SetKey(settingKey).
+		SetExpiredIncluded(true))
+
+	if err != nil {
+		return false, err
+	}
+
+	if target == nil || target.IsDeletion() {
+		for _, setting := range list {
+			if err := store.SettingSoftDelete(ctx, setting); err != nil {
+				return false, err
+			}
+		}
+
+		return len(list) > 0, nil
+	}
+
+	if len(list) > 0 && list[0].GetValue() == target.GetNewValue() && !list[0].IsExpired() {
+		return false, nil
+	}
+
+	if err := store.Set(ctx, settingKey, target.GetNewValue()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// historyStateAt returns the last change recorded up to the time
+// for every key matching the condition, by the key of the store
+func (store *store) historyStateAt(ctx context.Context, keyCondition exp.Expression, at time.Time) (map[string]SettingHistoryEntryInterface, error) {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.historyTableName).
+		Prepared(true).
+		Where(goqu.C(COLUMN_TENANT_ID).Eq(store.tenantID)).
+		Where(keyCondition).
+		Where(goqu.C(COLUMN_CREATED_AT).Lte(carbon.CreateFromStdTime(at, carbon.UTC).ToDateTimeString(carbon.UTC))).
+		Order(goqu.C(COLUMN_CREATED_AT).Asc(), goqu.C(COLUMN_VERSION).Asc()).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("history", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	states := map[string]SettingHistoryEntryInterface{}
+
+	for _, row := range rows {
+		entry, err := store.historyEntryFromRow(row)
+
+		if err != nil {
+			return nil, err
+		}
+
+		states[entry.GetKey()] = entry
+	}
+
+	return states, nil
+}
+
+// historyFindByVersion returns the recorded change of the key with the version
+func (store *store) historyFindByVersion(ctx context.Context, settingKey string, version int64) (SettingHistoryEntryInterface, error) {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		From(store.historyTableName).
+		Prepared(true).
+		Where(goqu.C(COLUMN_TENANT_ID).Eq(store.tenantID)).
+		Where(goqu.C(COLUMN_SETTING_KEY).Eq(store.namespacedKey(settingKey))).
+		Where(goqu.C(COLUMN_VERSION).Eq(version)).
+		Limit(1).
+		ToSQL()
+
+	if errSql != nil {
+		return nil, errSql
+	}
+
+	store.logSql("history", sqlStr, params...)
+
+	rows, err := store.selectToMapString(ctx, sqlStr, params...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) < 1 {
+		return nil, errors.New("settingstore > rollback. version not found in the history of the key")
+	}
+
+	return store.historyEntryFromRow(rows[0])
+}
@@ -0,0 +1,193 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dromara/carbon/v2"
+)
+
+func TestStoreRollbackToVersion(t *testing.T) {
+	_, store := initHistoryStore(t)
+
+	ctx := context.Background()
+
+	for _, value := range []string{"a", "b", "c"} {
+		if err := store.Set(ctx, "app.name", value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	if err := store.Rollback(ctx, "app.name", 1); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.Get(ctx, "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "a" {
+		t.Fatal("Value MUST be rolled back to version 1, found:", value)
+	}
+
+	entries, err := store.SettingHistory(ctx, "app.name", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 4 {
+		t.Fatal("Rollback MUST be recorded as a new change, found:", len(entries))
+	}
+
+	if entries[0].GetVersion() != 4 || entries[0].GetOldValue() != "c" || entries[0].GetNewValue() != "a" {
+		t.Fatal("unexpected entry:", entries[0].Data())
+	}
+
+	if err := store.Delete(ctx, "app.name"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// resurrects the deleted setting
+	if err := store.Rollback(ctx, "app.name", int64(2)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err = store.Get(ctx, "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "b" {
+		t.Fatal("Deleted setting MUST be restored, found:", value)
+	}
+
+	// back to the deletion
+	if err := store.Rollback(ctx, "app.name", 5); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	has, err := store.Has(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if has {
+		t.Fatal("Setting MUST be removed when rolled back to its deletion")
+	}
+
+	if err := store.Rollback(ctx, "app.name", 100); err == nil {
+		t.Fatal("Rollback MUST fail for an unknown version")
+	}
+
+	if err := store.Rollback(ctx, "app.name", "1"); err == nil {
+		t.Fatal("Rollback MUST fail for a version which is not a number or a time")
+	}
+}
+
+func TestStoreRollbackPrefix(t *testing.T) {
+	db, store := initHistoryStore(t)
+
+	ctx := context.Background()
+
+	mail := store.Namespace("mail")
+
+	if err := mail.Set(ctx, "host", "smtp.a.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := mail.Set(ctx, "port", "25"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// move the changes so far two days back in time
+	if _, err := db.Exec(`UPDATE setting_history SET created_at = ?`, carbon.Now(carbon.UTC).SubDays(2).ToDateTimeString(carbon.UTC)); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := mail.Set(ctx, "host", "smtp.b.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := mail.Delete(ctx, "port"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := mail.Set(ctx, "user", "admin"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "app.name", "My New App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := mail.RollbackPrefix(ctx, "", time.Now().Add(-24*time.Hour)); err == nil {
+		t.Fatal("RollbackPrefix MUST fail for an empty prefix")
+	}
+
+	changed, err := store.RollbackPrefix(ctx, "mail.", time.Now().Add(-24*time.Hour))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if changed != 3 {
+		t.Fatal("RollbackPrefix MUST change 3 settings, found:", changed)
+	}
+
+	expected := map[string]string{"host": "smtp.a.com", "port": "25", "user": ""}
+
+	for key, expectedValue := range expected {
+		value, err := mail.Get(ctx, key, "")
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if value != expectedValue {
+			t.Fatalf("Setting %s MUST be %q, found: %q", key, expectedValue, value)
+		}
+	}
+
+	value, err := store.Get(ctx, "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "My New App" {
+		t.Fatal("Settings outside the prefix MUST NOT be rolled back, found:", value)
+	}
+
+	entries, err := mail.SettingHistory(ctx, "user", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetAction() != HISTORY_ACTION_SOFT_DELETE {
+		t.Fatal("Removal of a setting created later MUST be recorded, found:", len(entries))
+	}
+}
+
+func TestStoreRollbackHistoryNotEnabled(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	if err := store.Rollback(context.Background(), "app.name", 1); !errors.Is(err, errHistoryNotEnabled) {
+		t.Fatal("Rollback MUST fail if the history is not enabled, found:", err)
+	}
+}
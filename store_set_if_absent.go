@@ -0,0 +1,184 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+)
+
+// setIfAbsentResult is the outcome of setIfAbsent, shared by the
+// concurrent callers for the same key
+type setIfAbsentResult struct {
+	value   string
+	created bool
+}
+
+// SetIfAbsent saves the value by key, only if the setting does not exist,
+// i.e. to persist a secret generated on first boot
+//
+// Of concurrent callers, in this and in other processes, exactly one
+// creates the setting, and all of them get the value it was created with.
+// In this process the concurrent callers for the same key share a single
+// round trip. An expired setting is treated as absent, and replaced
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - value: the value to save, if the setting does not exist
+//
+// Returns:
+// - stored - the value of the setting, the one of the caller if created
+// - created - true if the setting was created by this call, false otherwise
+// - err - nil if no error, error otherwise
+func (st *store) SetIfAbsent(ctx context.Context, settingKey string, value string) (stored string, created bool, err error) {
+	if settingKey == "" {
+		return "", false, errors.New("settingstore > set if absent. key cannot be empty")
+	}
+
+	// set by the caller whose function runs, the others share its result
+	executed := false
+
+	result, err := st.flightDo("set\x00"+st.cacheKey(settingKey), func() (any, error) {
+		executed = true
+		return st.setIfAbsent(ctx, settingKey, value)
+	})
+
+	if err != nil {
+		return "", false, err
+	}
+
+	outcome := result.(setIfAbsentResult)
+
+	return outcome.value, outcome.created && executed, nil
+}
+
+// GetOrCompute gets the value by key, or computes and saves it, if the
+// setting does not exist
+//
+// In this process the concurrent callers for the same key wait for a
+// single computation. Across processes each may compute a value, but
+// exactly one of them is saved, and returned to all the callers
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting
+// - compute: the function computing the value of a missing setting
+//
+// Returns:
+// - string - the value of the setting
+// - error - nil if no error, the error of compute, error otherwise
+func (st *store) GetOrCompute(ctx context.Context, settingKey string, compute func() (string, error)) (string, error) {
+	if compute == nil {
+		return "", errors.New("settingstore > get or compute. compute function cannot be nil")
+	}
+
+	value, found, err := st.findValueByKey(ctx, settingKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	if found {
+		return value, nil
+	}
+
+	result, err := st.flightDo("compute\x00"+st.cacheKey(settingKey), func() (any, error) {
+		// saved by a computation which finished in the meantime
+		setting, err := st.settingFindByKey(ctx, settingKey)
+
+		if err != nil {
+			return "", err
+		}
+
+		if setting != nil {
+			return setting.GetValue(), nil
+		}
+
+		computed, err := compute()
+
+		if err != nil {
+			return "", err
+		}
+
+		outcome, err := st.setIfAbsent(ctx, settingKey, computed)
+
+		return outcome.value, err
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return result.(string), nil
+}
+
+// setIfAbsent creates the setting, unless a live setting which is not
+// expired exists, and returns the value of the setting
+func (st *store) setIfAbsent(ctx context.Context, settingKey string, value string) (setIfAbsentResult, error) {
+	result := setIfAbsentResult{}
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		for attempt := 0; attempt < conditionalWriteAttempts; attempt++ {
+			inserted, err := txStore.insertIfAbsent(ctx, NewSetting().
+				SetKey(settingKey).
+				SetValue(value))
+
+			if err != nil {
+				return err
+			}
+
+			if inserted {
+				result = setIfAbsentResult{value: value, created: true}
+				return nil
+			}
+
+			list, err := txStore.settingList(ctx, SettingQuery().
+				SetKey(settingKey).
+				SetExpiredIncluded(true).
+				SetLimit(1))
+
+			if err != nil {
+				return err
+			}
+
+			if len(list) < 1 {
+				continue // deleted concurrently
+			}
+
+			if !isPastDateTime(list[0].GetExpiresAt()) {
+				result = setIfAbsentResult{value: list[0].GetValue()}
+				return nil
+			}
+
+			replaced, err := txStore.replaceExpired(ctx, list[0], value)
+
+			if err != nil {
+				return err
+			}
+
+			if replaced {
+				result = setIfAbsentResult{value: value, created: true}
+				return nil
+			}
+
+			// replaced concurrently, so read on the next attempt
+		}
+
+		return ErrConflict
+	})
+
+	return result, err
+}
+
+// flightDo runs the function once for the concurrent callers with the
+// same key in this process, and shares its result. A store bound to a
+// transaction runs it on its own, as the other callers cannot see the
+// uncommitted writes of the transaction
+func (store *store) flightDo(key string, fn func() (any, error)) (any, error) {
+	if store.flights == nil || store.transaction != nil {
+		return fn()
+	}
+
+	result, err, _ := store.flights.Do(key, fn)
+
+	return result, err
+}
@@ -0,0 +1,222 @@
+package settingstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func initSharedMemoryStore(t *testing.T) (*sql.DB, StoreInterface) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	return db, store
+}
+
+func TestStoreSetIfAbsent(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	stored, created, err := store.SetIfAbsent(ctx, "app.secret", "first")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !created || stored != "first" {
+		t.Fatalf("Missing setting MUST be created, found: %q %v", stored, created)
+	}
+
+	stored, created, err = store.SetIfAbsent(ctx, "app.secret", "second")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if created || stored != "first" {
+		t.Fatalf("Existing value MUST be kept, found: %q %v", stored, created)
+	}
+
+	if _, _, err := store.SetIfAbsent(ctx, "", "value"); err == nil {
+		t.Fatal("Empty key MUST be rejected")
+	}
+}
+
+func TestStoreSetIfAbsentExpired(t *testing.T) {
+	db, store := initSharedMemoryStore(t)
+
+	ctx := context.Background()
+
+	if err := store.SetWithTTL(ctx, "app.token", "old", 60); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := db.Exec(`UPDATE setting SET expires_at = '2020-01-01 00:00:00'`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	stored, created, err := store.SetIfAbsent(ctx, "app.token", "new")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !created || stored != "new" {
+		t.Fatalf("Expired setting MUST be replaced, found: %q %v", stored, created)
+	}
+
+	value, err := store.Get(ctx, "app.token", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "new" {
+		t.Fatal("Replaced value MUST be saved, found:", value)
+	}
+}
+
+func TestStoreSetIfAbsentConcurrent(t *testing.T) {
+	_, store := initSharedMemoryStore(t)
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var creators atomic.Int32
+
+	results := make([]string, 20)
+
+	for i := range results {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			stored, created, err := store.SetIfAbsent(ctx, "app.secret", "value-"+strconv.Itoa(i))
+
+			if err != nil {
+				t.Error("unexpected error:", err)
+				return
+			}
+
+			if created {
+				creators.Add(1)
+			}
+
+			results[i] = stored
+		}(i)
+	}
+
+	wg.Wait()
+
+	if creators.Load() != 1 {
+		t.Fatal("Setting MUST be created by a single caller, found:", creators.Load())
+	}
+
+	for _, result := range results {
+		if result != results[0] {
+			t.Fatalf("All callers MUST get the same value, found: %q and %q", results[0], result)
+		}
+	}
+}
+
+func TestStoreGetOrCompute(t *testing.T) {
+	_, store := initSharedMemoryStore(t)
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var computations atomic.Int32
+
+	results := make([]string, 20)
+
+	for i := range results {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			value, err := store.GetOrCompute(ctx, "report.total", func() (string, error) {
+				computations.Add(1)
+				time.Sleep(10 * time.Millisecond) // an expensive computation
+				return "42", nil
+			})
+
+			if err != nil {
+				t.Error("unexpected error:", err)
+				return
+			}
+
+			results[i] = value
+		}(i)
+	}
+
+	wg.Wait()
+
+	if computations.Load() != 1 {
+		t.Fatal("Value MUST be computed once, found:", computations.Load())
+	}
+
+	for _, result := range results {
+		if result != "42" {
+			t.Fatal("All callers MUST get the computed value, found:", result)
+		}
+	}
+}
+
+func TestStoreGetOrComputeError(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	errCompute := errors.New("service unavailable")
+
+	_, err = store.GetOrCompute(ctx, "report.total", func() (string, error) {
+		return "", errCompute
+	})
+
+	if !errors.Is(err, errCompute) {
+		t.Fatal("Error of compute MUST be returned, found:", err)
+	}
+
+	has, err := store.Has(ctx, "report.total")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if has {
+		t.Fatal("Failed computation MUST NOT be saved")
+	}
+}
@@ -0,0 +1,191 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/dromara/carbon/v2"
+)
+
+// sweeper is the background worker which periodically purges
+// the expired and the long soft deleted settings
+type sweeper struct {
+	interval  time.Duration
+	retention time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+// Close stops the background expiry sweeper, if it was started
+//
+// It waits for a sweep in progress to finish, or for the context
+// to be done, whichever comes first. It is safe to call Close
+// multiple times
+//
+// Parameters:
+// - ctx: the context
+//
+// Returns:
+// - error - nil if no error, the context error if it was done first
+func (store *store) Close(ctx context.Context) error {
+	if store.sweeper == nil {
+		return nil
+	}
+
+	store.sweeper.stopOnce.Do(func() {
+		close(store.sweeper.stop)
+	})
+
+	select {
+	case <-store.sweeper.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PurgeExpired hard deletes the settings which have expired
+//
+// The deletions are recorded in the history and the audit trail,
+// like the deletions of SettingDelete
+//
+// Parameters:
+// - ctx: the context
+//
+// Returns:
+// - int64 - the number of settings removed
+// - error - nil if no error, error otherwise
+func (store *store) PurgeExpired(ctx context.Context) (int64, error) {
+	expired := goqu.C(COLUMN_EXPIRES_AT).
+		Lte(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC))
+
+	return store.purge(ctx, expired)
+}
+
+// PurgeSoftDeleted hard deletes the settings which were soft deleted
+// more than the specified duration ago
+//
+// It is what the background sweeper runs, and can be called directly
+// for cron-style clean ups. Like PurgeExpired, it records the deletions
+// of the settings which were not soft deleted. The settings it removes
+// were all soft deleted, and recorded as deleted then, so no new changes
+// are recorded, while the cache is invalidated and the revision bumped
+//
+// Parameters:
+// - ctx: the context
+// - olderThan: how long ago the settings must have been soft deleted
+//
+// Returns:
+// - int64 - the number of settings removed
+// - error - nil if no error, error otherwise
+func (store *store) PurgeSoftDeleted(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if olderThan < 0 {
+		return 0, errors.New("settingstore > purge soft deleted. olderThan cannot be negative")
+	}
+
+	softDeletedBefore := carbon.CreateFromStdTime(time.Now().UTC().Add(-olderThan), carbon.UTC).
+		ToDateTimeString(carbon.UTC)
+
+	softDeleted := goqu.C(COLUMN_SOFT_DELETED_AT).Lt(softDeletedBefore)
+
+	return store.purge(ctx, softDeleted)
+}
+
+// purge hard deletes the settings matching the condition, recording
+// the deletions of the ones which were not soft deleted, invalidating
+// the cache and bumping the revision
+func (store *store) purge(ctx context.Context, condition goqu.Expression) (int64, error) {
+	if store.db == nil {
+		return 0, errors.New("settingstore > purge. db cannot be nil")
+	}
+
+	conditions := []exp.Expression{
+		condition,
+		store.purgeTenantExpression(),
+		store.namespaceExpression(),
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Delete(store.settingTableName).
+		Prepared(true).
+		Where(conditions...).
+		ToSQL()
+
+	if errSql != nil {
+		return 0, errSql
+	}
+
+	store.logSql("purge", sqlStr, params...)
+
+	return store.executeDeletion(ctx, conditions, HISTORY_ACTION_DELETE, sqlStr, params...)
+}
+
+// startSweeper starts the background expiry sweeper
+func (store *store) startSweeper(interval time.Duration, retention time.Duration) {
+	store.sweeper = &sweeper{
+		interval:  interval,
+		retention: retention,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go store.runSweeper()
+}
+
+// runSweeper runs the sweeps at the configured interval, until stopped by Close
+func (store *store) runSweeper() {
+	defer close(store.sweeper.done)
+
+	ticker := time.NewTicker(store.sweeper.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-store.sweeper.stop:
+			return
+		case <-ticker.C:
+			store.sweep()
+		}
+	}
+}
+
+// sweep purges the expired settings, and the soft deleted settings
+// older than the retention (if one is configured)
+func (store *store) sweep() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-store.sweeper.stop:
+			cancel() // abort the sweep in progress on Close
+		case <-ctx.Done():
+		}
+	}()
+
+	expiredCount, err := store.PurgeExpired(ctx)
+
+	if err != nil {
+		store.sqlLogger.Error("settingstore: purging expired settings failed", slog.String("error", err.Error()))
+	} else if expiredCount > 0 {
+		store.sqlLogger.Info("settingstore: purged expired settings", slog.Int64("count", expiredCount))
+	}
+
+	if store.sweeper.retention <= 0 {
+		return
+	}
+
+	softDeletedCount, err := store.PurgeSoftDeleted(ctx, store.sweeper.retention)
+
+	if err != nil {
+		store.sqlLogger.Error("settingstore: purging soft deleted settings failed", slog.String("error", err.Error()))
+	} else if softDeletedCount > 0 {
+		store.sqlLogger.Info("settingstore: purged soft deleted settings", slog.Int64("count", softDeletedCount))
+	}
+}
@@ -0,0 +1,76 @@
+package settingstore
+
+import (
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// errTenantScope is returned by every query of a store, which was
+// asked to switch from the tenant it is scoped to another one
+var errTenantScope = errors.New("settingstore: a tenant scoped store cannot be scoped to another tenant")
+
+// ForTenant returns a view of the store scoped to the tenant
+//
+// Every setting created through the view belongs to the tenant, and the
+// view can neither read nor write the settings of another tenant. The keys
+// are unique per tenant, so two tenants can each have their own "theme".
+//
+// The store itself works with the settings which belong to no tenant,
+// and can list the settings of any tenant with SettingQuery().SetTenantID.
+//
+// A scoped view cannot be rescoped, so it is safe to hand to the code
+// serving the tenant. ForTenant on a view scoped to another tenant returns
+// a view failing all its queries.
+//
+// Parameters:
+// - tenantID: the ID of the tenant, an empty ID scopes the view to
+// the settings which belong to no tenant
+//
+// Returns:
+// - StoreInterface - the tenant scoped view of the store
+func (store *store) ForTenant(tenantID string) StoreInterface {
+	view := *store
+	view.sweeper = nil
+
+	if store.tenantScoped && store.tenantID != tenantID {
+		view.scopeErr = errTenantScope
+		return &view
+	}
+
+	view.tenantID = tenantID
+	view.tenantScoped = true
+
+	return &view
+}
+
+// tenantExpression returns the condition limiting the rows
+// to the tenant of the store
+func (store *store) tenantExpression() exp.Expression {
+	return goqu.C(COLUMN_TENANT_ID).Eq(store.tenantID)
+}
+
+// tenantQueryExpression returns the condition limiting the rows to the
+// tenant requested by the query, which only the unscoped store honors
+func (store *store) tenantQueryExpression(options SettingQueryInterface) exp.Expression {
+	if !store.tenantScoped && options.HasTenantID() {
+		return goqu.C(COLUMN_TENANT_ID).Eq(options.TenantID())
+	}
+
+	if options.HasTenantID() && options.TenantID() != store.tenantID {
+		return goqu.L("1 = 0") // another tenant, nothing to see
+	}
+
+	return store.tenantExpression()
+}
+
+// purgeTenantExpression returns the condition limiting a purge to the
+// tenant of the store. The unscoped store purges all the tenants
+func (store *store) purgeTenantExpression() exp.Expression {
+	if !store.tenantScoped {
+		return goqu.And()
+	}
+
+	return store.tenantExpression()
+}
@@ -0,0 +1,124 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStoreForTenant(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	acme := store.ForTenant("acme")
+	globex := store.ForTenant("globex")
+
+	if err := acme.Set(ctx, "theme", "dark"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := globex.Set(ctx, "theme", "light"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "theme", "default"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for view, expected := range map[StoreInterface]string{acme: "dark", globex: "light", store: "default"} {
+		value, err := view.Get(ctx, "theme", "")
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if value != expected {
+			t.Fatal("The key MUST be unique per tenant, expected:", expected, "found:", value)
+		}
+	}
+
+	setting, err := acme.SettingFindByKey(ctx, "theme")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if setting.GetTenantID() != "acme" {
+		t.Fatal("The setting MUST belong to the tenant, found:", setting.GetTenantID())
+	}
+
+	found, err := globex.SettingFindByID(ctx, setting.GetID())
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if found != nil {
+		t.Fatal("A tenant MUST NOT find the setting of another tenant")
+	}
+
+	if err := globex.SettingDeleteByID(ctx, setting.GetID()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	setting.SetValue("hacked")
+
+	if err := globex.SettingUpdate(ctx, setting); !errors.Is(err, ErrConflict) {
+		t.Fatal("Update of the setting of another tenant MUST fail with ErrConflict, found:", err)
+	}
+
+	if value, _ := acme.Get(ctx, "theme", ""); value != "dark" {
+		t.Fatal("A tenant MUST NOT write the setting of another tenant, found:", value)
+	}
+
+	count, err := globex.SettingCount(ctx, SettingQuery().SetTenantID("acme"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 0 {
+		t.Fatal("A tenant MUST NOT count the settings of another tenant, found:", count)
+	}
+
+	count, err = store.SettingCount(ctx, SettingQuery().SetTenantID("acme"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("The unscoped store MUST count the settings of the requested tenant, found:", count)
+	}
+}
+
+func TestStoreForTenantCannotBeRescoped(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	acme := store.ForTenant("acme")
+
+	if err := acme.Namespace("billing").Set(ctx, "currency", "EUR"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value, _ := acme.ForTenant("acme").Get(ctx, "billing.currency", ""); value != "EUR" {
+		t.Fatal("Rescoping to the same tenant MUST be allowed, found:", value)
+	}
+
+	_, err = acme.ForTenant("globex").Get(ctx, "billing.currency", "")
+
+	if !errors.Is(err, errTenantScope) {
+		t.Fatal("Rescoping to another tenant MUST fail, found:", err)
+	}
+}
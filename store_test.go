@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/dromara/carbon/v2"
 	"github.com/gouniverse/sb"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -55,6 +58,34 @@ func initStore(filepath string) (StoreInterface, error) {
 	return store, nil
 }
 
+// sqlHook is a handler of the SQL log, which runs the function once, when
+// a statement starting with the prefix is about to be executed, i.e. to
+// interleave the writes of another store
+type sqlHook struct {
+	prefix string
+	fn     func()
+}
+
+func (h *sqlHook) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *sqlHook) Handle(_ context.Context, record slog.Record) error {
+	record.Attrs(func(attr slog.Attr) bool {
+		if h.fn != nil && attr.Key == "sql" && strings.HasPrefix(attr.Value.String(), h.prefix) {
+			fn := h.fn
+			h.fn = nil
+			fn()
+		}
+
+		return true
+	})
+
+	return nil
+}
+
+func (h *sqlHook) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *sqlHook) WithGroup(string) slog.Handler { return h }
+
 func TestStore_Create(t *testing.T) {
 	store, err := initStore(":memory:")
 
@@ -590,3 +621,908 @@ func TestStore_SettingUpdate(t *testing.T) {
 		t.Fatal("Value MUST be 'one two three', found: ", settingFound.GetValue())
 	}
 }
+
+func TestStore_SettingUpdateConflict(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	first, err := store.SettingFindByKey(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	second, err := store.SettingFindByKey(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if first.GetVersion() != 1 {
+		t.Fatal("Version MUST be 1, found: ", first.GetVersion())
+	}
+
+	if err := store.SettingUpdate(ctx, first.SetValue("First")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if first.GetVersion() != 2 {
+		t.Fatal("Version MUST be incremented, found: ", first.GetVersion())
+	}
+
+	if err := store.SettingUpdate(ctx, second.SetValue("Second")); !errors.Is(err, ErrConflict) {
+		t.Fatal("Update of a stale setting MUST fail with ErrConflict, found:", err)
+	}
+
+	// the updated setting can be updated again
+	if err := store.SettingUpdate(ctx, first.SetValue("First again")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.Get(ctx, "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "First again" {
+		t.Fatal("Value MUST be 'First again', found: ", value)
+	}
+
+	if err := store.SettingDelete(ctx, first); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SettingUpdate(ctx, first.SetValue("Deleted")); !errors.Is(err, ErrConflict) {
+		t.Fatal("Update of a deleted setting MUST fail with ErrConflict, found:", err)
+	}
+}
+
+func TestStore_SettingUpdateKey(t *testing.T) {
+	_, store := initHistoryStore(t)
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	setting, err := store.SettingFindByKey(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	stale, err := store.SettingFindByKey(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SettingUpdate(ctx, setting.SetKey("app.title")); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if setting.GetVersion() != 2 {
+		t.Fatal("Version MUST be incremented, found: ", setting.GetVersion())
+	}
+
+	if value, _ := store.Get(ctx, "app.name", "missing"); value != "missing" {
+		t.Fatal("Old key MUST NOT be found, found: ", value)
+	}
+
+	if value, _ := store.Get(ctx, "app.title", ""); value != "My App" {
+		t.Fatal("New key MUST have the value, found: ", value)
+	}
+
+	if err := store.SettingUpdate(ctx, stale.SetKey("app.label")); !errors.Is(err, ErrConflict) {
+		t.Fatal("Update of the key of a stale setting MUST fail with ErrConflict, found:", err)
+	}
+}
+
+func TestStore_AutomigrateAddsMissingColumns(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	_, err = db.Exec(`CREATE TABLE setting (id TEXT PRIMARY KEY, setting_key TEXT, setting_value TEXT, created_at DATETIME, updated_at DATETIME, soft_deleted_at DATETIME)`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO setting VALUES ('1', 'legacy', 'value', '2020-01-01 00:00:00', '2020-01-01 00:00:00', '9999-12-31 23:59:59')`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	store, err := NewStore(NewStoreOptions{
+		DB:               db,
+		SettingTableName: "setting",
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	err = store.AutoMigrate(context.Background())
+
+	if err != nil {
+		t.Fatal("Automigrate failed: " + err.Error())
+	}
+
+	setting, err := store.SettingFindByKey(context.Background(), "legacy")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if setting == nil {
+		t.Fatal("Setting MUST NOT be nil")
+	}
+
+	if !strings.Contains(setting.GetExpiresAt(), sb.MAX_DATETIME) {
+		t.Fatal("ExpiresAt MUST be backfilled, found: ", setting.GetExpiresAt())
+	}
+
+	if setting.GetVersion() != 1 {
+		t.Fatal("Version MUST be backfilled, found: ", setting.GetVersion())
+	}
+}
+
+func TestStore_SetWithTTL(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	err = store.SetWithTTL(ctx, "token", "abc", 600)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	setting, err := store.SettingFindByKey(ctx, "token")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if setting == nil {
+		t.Fatal("Setting MUST NOT be nil")
+	}
+
+	diff := setting.GetExpiresAtCarbon().DiffAbsInSeconds(carbon.Now(carbon.UTC))
+
+	if diff < 590 || diff > 610 {
+		t.Fatal("ExpiresAt MUST be in 600 seconds, found: ", setting.GetExpiresAt())
+	}
+
+	err = store.Set(ctx, "token", "def")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	setting, err = store.SettingFindByKey(ctx, "token")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !strings.Contains(setting.GetExpiresAt(), sb.MAX_DATETIME) {
+		t.Fatal("Set MUST remove the expiry, found: ", setting.GetExpiresAt())
+	}
+}
+
+func TestStore_ExpiredSettingIsAbsent(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	setting := NewSetting().
+		SetKey("maintenance").
+		SetValue(`{"enabled":true}`).
+		SetExpiresAt(carbon.Now(carbon.UTC).SubSeconds(1).ToDateTimeString(carbon.UTC))
+
+	err = store.SettingCreate(ctx, setting)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if !setting.IsExpired() {
+		t.Fatal("Setting MUST be expired")
+	}
+
+	value, err := store.Get(ctx, "maintenance", "default")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "default" {
+		t.Fatal("Get MUST return the default value, found: ", value)
+	}
+
+	valueMap, err := store.GetMap(ctx, "maintenance", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if valueMap != nil {
+		t.Fatal("GetMap MUST return the default value, found: ", valueMap)
+	}
+
+	has, err := store.Has(ctx, "maintenance")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if has {
+		t.Fatal("Has MUST return false for an expired setting")
+	}
+
+	list, err := store.SettingList(ctx, SettingQuery().
+		SetKey("maintenance").
+		SetExpiredIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(list) != 1 {
+		t.Fatal("Expired setting MUST be listed when requested, found: ", len(list))
+	}
+
+	err = store.SetAny(ctx, "maintenance", map[string]any{"enabled": false}, 60)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	count, err := store.SettingCount(ctx, SettingQuery().
+		SetKey("maintenance").
+		SetExpiredIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("Expired setting MUST be reused, found: ", count)
+	}
+
+	valueAny, err := store.GetAny(ctx, "maintenance", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if valueAny == nil {
+		t.Fatal("GetAny MUST return the renewed value")
+	}
+}
+
+func TestStore_PurgeExpired(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	expired := NewSetting().
+		SetKey("expired").
+		SetValue("value").
+		SetExpiresAt(carbon.Now(carbon.UTC).SubSeconds(10).ToDateTimeString(carbon.UTC))
+
+	live := NewSetting().
+		SetKey("live").
+		SetValue("value")
+
+	for _, setting := range []SettingInterface{expired, live} {
+		if err := store.SettingCreate(ctx, setting); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	count, err := store.PurgeExpired(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("PurgeExpired MUST remove 1 setting, removed: ", count)
+	}
+
+	total, err := store.SettingCount(ctx, SettingQuery().SetExpiredIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if total != 1 {
+		t.Fatal("Only the live setting MUST remain, found: ", total)
+	}
+}
+
+func TestStore_PurgeSoftDeleted(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	old := NewSetting().
+		SetKey("old").
+		SetValue("value").
+		SetSoftDeletedAt(carbon.Now(carbon.UTC).SubDays(40).ToDateTimeString(carbon.UTC))
+
+	recent := NewSetting().
+		SetKey("recent").
+		SetValue("value").
+		SetSoftDeletedAt(carbon.Now(carbon.UTC).SubDays(1).ToDateTimeString(carbon.UTC))
+
+	live := NewSetting().
+		SetKey("live").
+		SetValue("value")
+
+	for _, setting := range []SettingInterface{old, recent, live} {
+		if err := store.SettingCreate(ctx, setting); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	count, err := store.PurgeSoftDeleted(ctx, 30*24*time.Hour)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("PurgeSoftDeleted MUST remove 1 setting, removed: ", count)
+	}
+
+	total, err := store.SettingCount(ctx, SettingQuery().SetSoftDeletedIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if total != 2 {
+		t.Fatal("The recent and the live settings MUST remain, found: ", total)
+	}
+}
+
+func TestStore_PurgeRecorded(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	settingsStore, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		HistoryTableName:   "setting_history",
+		AuditTableName:     "setting_audit",
+		RevisionTableName:  "setting_revision",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := WithActor(context.Background(), "sweeper")
+
+	expired := NewSetting().
+		SetKey("expired").
+		SetValue("value").
+		SetExpiresAt(carbon.Now(carbon.UTC).SubSeconds(10).ToDateTimeString(carbon.UTC))
+
+	if err := settingsStore.SettingCreate(ctx, expired); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	old := NewSetting().
+		SetKey("old").
+		SetValue("value")
+
+	if err := settingsStore.SettingCreate(ctx, old); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := settingsStore.SettingSoftDelete(ctx, old); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// soft deleted long ago
+	_, err = db.Exec("UPDATE setting SET soft_deleted_at = ? WHERE setting_key = 'old'",
+		carbon.Now(carbon.UTC).SubDays(40).ToDateTimeString(carbon.UTC))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	revision, err := settingsStore.revisionCurrent(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count, err := settingsStore.PurgeExpired(ctx); err != nil || count != 1 {
+		t.Fatal("PurgeExpired MUST remove 1 setting, removed: ", count, err)
+	}
+
+	if count, err := settingsStore.PurgeSoftDeleted(ctx, 30*24*time.Hour); err != nil || count != 1 {
+		t.Fatal("PurgeSoftDeleted MUST remove 1 setting, removed: ", count, err)
+	}
+
+	purgedRevision, err := settingsStore.revisionCurrent(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if purgedRevision != revision+2 {
+		t.Fatal("Each purge MUST bump the revision, found: ", revision, purgedRevision)
+	}
+
+	if _, err := settingsStore.PurgeExpired(ctx); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if current, _ := settingsStore.revisionCurrent(ctx); current != purgedRevision {
+		t.Fatal("Purge which removes nothing MUST NOT bump the revision, found: ", current)
+	}
+
+	entries, err := settingsStore.SettingHistory(ctx, "expired", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetAction() != HISTORY_ACTION_DELETE || entries[0].GetOldValue() != "value" {
+		t.Fatal("Purge of the expired setting MUST be recorded in the history, found: ", entries)
+	}
+
+	entries, err = settingsStore.SettingHistory(ctx, "old", nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(entries) != 2 || entries[0].GetAction() != HISTORY_ACTION_SOFT_DELETE {
+		t.Fatal("Soft deleted setting MUST NOT be recorded as deleted again, found: ", entries)
+	}
+
+	auditEntries, err := settingsStore.SettingAudit(ctx, SettingAuditQuery().SetKeyPrefix("expired"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(auditEntries) != 2 || auditEntries[0].GetAction() != HISTORY_ACTION_DELETE || auditEntries[0].GetActor() != "sweeper" {
+		t.Fatal("Purge of the expired setting MUST be audited, found: ", auditEntries)
+	}
+}
+
+func TestStore_ExpirySweeper(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                   db,
+		SettingTableName:     "setting",
+		AutomigrateEnabled:   true,
+		ExpirySweepInterval:  10 * time.Millisecond,
+		SoftDeletedRetention: time.Hour,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	expired := NewSetting().
+		SetKey("expired").
+		SetValue("value").
+		SetExpiresAt(carbon.Now(carbon.UTC).SubSeconds(10).ToDateTimeString(carbon.UTC))
+
+	if err := store.SettingCreate(ctx, expired); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	for {
+		count, err := store.SettingCount(ctx, SettingQuery().SetExpiredIncluded(true))
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if count == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Sweeper MUST purge the expired setting")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := store.Close(ctx); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Close(ctx); err != nil {
+		t.Fatal("Close MUST be safe to call twice, error:", err)
+	}
+}
+
+func TestStore_Cache(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+		CacheEnabled:       true,
+		CacheMissingKeys:   true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	value, err := store.Get(ctx, "app.name", "default")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "default" {
+		t.Fatal("Get MUST return the default value, found: ", value)
+	}
+
+	// served from the negative cache
+	if _, err := store.Get(ctx, "app.name", "default"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if stats := store.CacheStats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+
+	// invalidates the negative cache entry
+	if err := store.Set(ctx, "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err = store.Get(ctx, "app.name", "default")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "My App" {
+		t.Fatal("Get MUST return the new value, found: ", value)
+	}
+
+	setting, err := store.SettingFindByKey(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SettingSoftDelete(ctx, setting); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err = store.Get(ctx, "app.name", "default")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "default" {
+		t.Fatal("Get MUST NOT return a soft deleted value, found: ", value)
+	}
+
+	if stats := store.CacheStats(); stats.Hits != 1 || stats.Misses != 3 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+}
+
+func TestStore_CacheRevisionInvalidation(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	// two stores sharing the same table, as two app instances would
+	newStore := func() StoreInterface {
+		store, err := NewStore(NewStoreOptions{
+			DB:                         db,
+			SettingTableName:           "setting",
+			AutomigrateEnabled:         true,
+			CacheEnabled:               true,
+			RevisionTableName:          "setting_revision",
+			CacheRevisionCheckInterval: 20 * time.Millisecond,
+		})
+
+		if err != nil {
+			t.Fatal("Store could not be created: ", err.Error())
+		}
+
+		return store
+	}
+
+	instance1 := newStore()
+	instance2 := newStore()
+
+	ctx := context.Background()
+
+	if err := instance1.Set(ctx, "mail.from", "a@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := instance2.Get(ctx, "mail.from", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "a@test.com" {
+		t.Fatal("unexpected value:", value)
+	}
+
+	if err := instance1.Set(ctx, "mail.from", "b@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// within the check interval the cached value may be served
+	if _, err := instance2.Get(ctx, "mail.from", ""); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	value, err = instance2.Get(ctx, "mail.from", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "b@test.com" {
+		t.Fatal("Get MUST return the value written by the other instance, found: ", value)
+	}
+
+	// own writes do not flush the whole cache
+	if _, err := instance1.Get(ctx, "mail.from", ""); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := instance1.Set(ctx, "mail.to", "c@test.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	hitsBefore := instance1.CacheStats().Hits
+
+	if _, err := instance1.Get(ctx, "mail.from", ""); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if instance1.CacheStats().Hits != hitsBefore+1 {
+		t.Fatal("An own write MUST NOT flush the cache")
+	}
+}
+
+func TestStore_SettingListKeyPrefixAndLike(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	keys := []string{"mail.smtp.host", "mail.smtp.port", "mail.from", "Mail.smtp.user", "mail_smtp.host", "billing.stripe.key"}
+
+	for _, key := range keys {
+		if err := store.Set(ctx, key, "value"); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	settings, err := store.SettingList(ctx, SettingQuery().
+		SetKeyPrefix("mail.smtp.").
+		SetOrderBy(COLUMN_SETTING_KEY).
+		SetSortOrder("asc"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(settings) != 2 || settings[0].GetKey() != "mail.smtp.host" || settings[1].GetKey() != "mail.smtp.port" {
+		t.Fatal("SettingList MUST return the keys with the prefix only, found:", len(settings))
+	}
+
+	count, err := store.SettingCount(ctx, SettingQuery().SetKeyPrefix("mail_"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("The _ in the prefix MUST be matched literally, found:", count)
+	}
+
+	count, err = store.SettingCount(ctx, SettingQuery().SetKeyLike("*.smtp.*"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 3 {
+		t.Fatal("SettingCount MUST count the keys matching the pattern, found:", count)
+	}
+
+	count, err = store.SettingCount(ctx, SettingQuery().SetKeyLike("mail_smtp.?ost"))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("The _ in the pattern MUST be matched literally, found:", count)
+	}
+
+	if _, err := store.SettingCount(ctx, SettingQuery().SetKeyPrefix("")); err == nil {
+		t.Fatal("An empty key prefix MUST be rejected")
+	}
+}
+
+func TestStore_DeleteByPrefix(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	for _, key := range []string{"mail.smtp.host", "mail.smtp.port", "mail.from"} {
+		if err := store.Set(ctx, key, "value"); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	deleted, err := store.DeleteByPrefix(ctx, "mail.smtp.")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if deleted != 2 {
+		t.Fatal("DeleteByPrefix MUST delete 2 settings, found:", deleted)
+	}
+
+	count, err := store.SettingCount(ctx, SettingQuery().SetSoftDeletedIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("Only the setting outside the prefix MUST remain, found:", count)
+	}
+
+	if _, err := store.DeleteByPrefix(ctx, ""); err == nil {
+		t.Fatal("An empty key prefix MUST be rejected")
+	}
+}
+
+func TestStore_SoftDeleteByPrefix(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	for _, key := range []string{"mail.smtp.host", "mail.smtp.port", "mail.from"} {
+		if err := store.Set(ctx, key, "value"); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	softDeleted, err := store.SoftDeleteByPrefix(ctx, "mail.smtp.")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if softDeleted != 2 {
+		t.Fatal("SoftDeleteByPrefix MUST soft delete 2 settings, found:", softDeleted)
+	}
+
+	has, err := store.Has(ctx, "mail.smtp.host")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if has {
+		t.Fatal("The soft deleted setting MUST NOT be found")
+	}
+
+	count, err := store.SettingCount(ctx, SettingQuery().SetKeyPrefix("mail.smtp.").SetSoftDeletedIncluded(true))
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 2 {
+		t.Fatal("The soft deleted settings MUST be kept, found:", count)
+	}
+
+	softDeleted, err = store.SoftDeleteByPrefix(ctx, "mail.smtp.")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if softDeleted != 0 {
+		t.Fatal("The soft deleted settings MUST NOT be soft deleted again, found:", softDeleted)
+	}
+}
@@ -0,0 +1,250 @@
+package settingstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gouniverse/base/database"
+	"github.com/gouniverse/sb"
+)
+
+// transaction is the state shared by the stores bound to the same transaction
+type transaction struct {
+	tx          *sql.Tx
+	afterCommit []func()
+
+	// external is true for a transaction started and committed by the caller
+	external bool
+
+	// savepoints counts the savepoints created, to name them uniquely
+	savepoints int
+}
+
+// RunInTransaction runs the function with a store bound to a transaction
+//
+// The transaction is committed if the function returns no error, or rolled
+// back if it returns an error or panics. All the reads and writes made with
+// the store passed to the function are part of the transaction.
+//
+// Called on a store which is already bound to a transaction, the function
+// runs in a savepoint of it, which is rolled back on its own if the function
+// fails. Savepoints are supported on SQLite, MySQL, Postgres and SQL Server.
+//
+// Parameters:
+// - ctx: the context
+// - fn: the function to run, with the store bound to the transaction
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) RunInTransaction(ctx context.Context, fn func(tx StoreInterface) error) error {
+	if fn == nil {
+		return errors.New("settingstore > run in transaction. function cannot be nil")
+	}
+
+	run := func(txStore *store) error {
+		return fn(txStore)
+	}
+
+	if st.transaction != nil {
+		return st.inSavepoint(ctx, run)
+	}
+
+	return st.inTransaction(ctx, run)
+}
+
+// WithTx returns a view of the store bound to a transaction of the caller,
+// so that the settings are written as part of it
+//
+// The caller commits or rolls back the transaction. The cache cannot know
+// when, so the changed keys are dropped from it as they are written, and
+// may be cached again with their old values until the commit. Enable the
+// revision counter to have them dropped again once committed.
+//
+// Parameters:
+// - tx: the transaction
+//
+// Returns:
+// - StoreInterface - the view of the store bound to the transaction
+func (store *store) WithTx(tx *sql.Tx) StoreInterface {
+	view := *store
+	view.sweeper = nil
+
+	if tx == nil {
+		view.scopeErr = errors.New("settingstore: transaction is nil")
+		return &view
+	}
+
+	view.transaction = &transaction{tx: tx, external: true}
+
+	return &view
+}
+
+// queryable returns the transaction the store is bound to, or the database
+func (store *store) queryable() database.QueryableInterface {
+	if store.transaction != nil {
+		return store.transaction.tx
+	}
+
+	return store.db
+}
+
+// executeSql executes the SQL statement in the transaction the store
+// is bound to, or directly on the database
+func (store *store) executeSql(ctx context.Context, sqlStr string, params ...any) (sql.Result, error) {
+	if store.db == nil {
+		return nil, errors.New("settingstore: database is nil")
+	}
+
+	if store.scopeErr != nil {
+		return nil, store.scopeErr
+	}
+
+	return database.Execute(database.Context(ctx, store.queryable()), sqlStr, params...)
+}
+
+// selectToMapString runs the SQL query in the transaction the store
+// is bound to, or directly on the database
+func (store *store) selectToMapString(ctx context.Context, sqlStr string, params ...any) ([]map[string]string, error) {
+	if store.db == nil {
+		return []map[string]string{}, errors.New("settingstore: database is nil")
+	}
+
+	if store.scopeErr != nil {
+		return []map[string]string{}, store.scopeErr
+	}
+
+	return database.SelectToMapString(database.Context(ctx, store.queryable()), sqlStr, params...)
+}
+
+// inTransaction runs the function with a store bound to a transaction
+//
+// If the store is already bound to a transaction, the function joins it.
+// Otherwise a new transaction is started, and committed if the function
+// returns no error, or rolled back if it does.
+//
+// Parameters:
+// - ctx: the context
+// - fn: the function to run, with the store bound to the transaction
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (store *store) inTransaction(ctx context.Context, fn func(txStore *store) error) error {
+	if store.transaction != nil {
+		return fn(store)
+	}
+
+	if store.db == nil {
+		return errors.New("settingstore: database is nil")
+	}
+
+	tx, err := store.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	txStore := *store
+	txStore.transaction = &transaction{tx: tx}
+	txStore.sweeper = nil
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(&txStore); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			return errors.Join(err, errRollback)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, afterCommit := range txStore.transaction.afterCommit {
+		afterCommit()
+	}
+
+	return nil
+}
+
+// inSavepoint runs the function in a savepoint of the transaction the store
+// is bound to, which is released if the function returns no error, or rolled
+// back if it does
+//
+// Parameters:
+// - ctx: the context
+// - fn: the function to run, with the store bound to the transaction
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (store *store) inSavepoint(ctx context.Context, fn func(txStore *store) error) error {
+	store.transaction.savepoints++
+
+	name := fmt.Sprintf("settingstore_%d", store.transaction.savepoints)
+
+	save, release, rollback, err := savepointSqls(store.dbDriverName, name)
+
+	if err != nil {
+		return err
+	}
+
+	store.logSql("savepoint", save)
+
+	if _, err := store.executeSql(ctx, save); err != nil {
+		return err
+	}
+
+	if err := fn(store); err != nil {
+		store.logSql("savepoint", rollback)
+
+		if _, errRollback := store.executeSql(ctx, rollback); errRollback != nil {
+			return errors.Join(err, errRollback)
+		}
+
+		return err
+	}
+
+	if release == "" {
+		return nil // released with the transaction
+	}
+
+	store.logSql("savepoint", release)
+
+	_, err = store.executeSql(ctx, release)
+
+	return err
+}
+
+// savepointSqls returns the statements creating, releasing and rolling back
+// to a savepoint in the dialect. The release is empty, if the dialect
+// releases the savepoints only with the transaction
+func savepointSqls(dialect string, name string) (save string, release string, rollback string, err error) {
+	switch dialect {
+	case sb.DIALECT_MYSQL, sb.DIALECT_POSTGRES, sb.DIALECT_SQLITE, "sqlite3":
+		return "SAVEPOINT " + name, "RELEASE SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, nil
+	case sb.DIALECT_MSSQL, "sqlserver":
+		return "SAVE TRANSACTION " + name, "", "ROLLBACK TRANSACTION " + name, nil
+	}
+
+	return "", "", "", errors.New("settingstore: nested transactions are not supported for the dialect " + dialect)
+}
+
+// afterCommit runs the function once the transaction the store is bound
+// to is committed, or immediately if the store is not in a transaction,
+// or in a transaction of the caller
+func (store *store) afterCommit(fn func()) {
+	if store.transaction == nil || store.transaction.external {
+		fn()
+		return
+	}
+
+	store.transaction.afterCommit = append(store.transaction.afterCommit, fn)
+}
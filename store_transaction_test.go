@@ -0,0 +1,273 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreRunInTransaction(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	err = store.RunInTransaction(ctx, func(tx StoreInterface) error {
+		if err := tx.Set(ctx, "smtp.user", "alice"); err != nil {
+			return err
+		}
+
+		return tx.Set(ctx, "smtp.password", "secret")
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	errRotate := errors.New("rotation failed")
+
+	err = store.RunInTransaction(ctx, func(tx StoreInterface) error {
+		if err := tx.Set(ctx, "smtp.user", "bob"); err != nil {
+			return err
+		}
+
+		// the transaction sees its own writes
+		if value, _ := tx.Get(ctx, "smtp.user", ""); value != "bob" {
+			t.Error("Transaction MUST see its own writes, found:", value)
+		}
+
+		return errRotate
+	})
+
+	if !errors.Is(err, errRotate) {
+		t.Fatal("The error of the function MUST be returned, found:", err)
+	}
+
+	value, err := store.Get(ctx, "smtp.user", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "alice" {
+		t.Fatal("Failed transaction MUST be rolled back, found:", value)
+	}
+}
+
+func TestStoreRunInTransactionClose(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                  db,
+		SettingTableName:    "setting",
+		AutomigrateEnabled:  true,
+		ExpirySweepInterval: time.Hour,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	err = store.RunInTransaction(ctx, func(tx StoreInterface) error {
+		return tx.Close(ctx)
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	select {
+	case <-store.sweeper.stop:
+		t.Fatal("Close of the transaction store MUST NOT stop the sweeper of the store")
+	default:
+	}
+
+	if err := store.Close(ctx); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestStoreRunInTransactionPanic(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("The panic MUST be propagated")
+			}
+		}()
+
+		store.RunInTransaction(ctx, func(tx StoreInterface) error {
+			if err := tx.Set(ctx, "app.name", "My App"); err != nil {
+				return err
+			}
+
+			panic("boom")
+		})
+	}()
+
+	has, err := store.Has(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if has {
+		t.Fatal("Panicking transaction MUST be rolled back")
+	}
+}
+
+func TestStoreRunInTransactionSavepoint(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	err = store.RunInTransaction(ctx, func(tx StoreInterface) error {
+		if err := tx.Set(ctx, "outer", "1"); err != nil {
+			return err
+		}
+
+		errInner := tx.RunInTransaction(ctx, func(inner StoreInterface) error {
+			if err := inner.Set(ctx, "inner.failed", "1"); err != nil {
+				return err
+			}
+
+			return errors.New("inner failed")
+		})
+
+		if errInner == nil {
+			t.Error("The error of the nested function MUST be returned")
+		}
+
+		return tx.RunInTransaction(ctx, func(inner StoreInterface) error {
+			return inner.Set(ctx, "inner.succeeded", "1")
+		})
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := map[string]bool{"outer": true, "inner.failed": false, "inner.succeeded": true}
+
+	for key, expectedHas := range expected {
+		has, err := store.Has(ctx, key)
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if has != expectedHas {
+			t.Fatalf("Setting %s MUST exist: %v, found: %v", key, expectedHas, has)
+		}
+	}
+}
+
+func TestStoreWithTx(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+		CacheEnabled:       true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := store.Get(ctx, "app.name", ""); err != nil { // cached
+		t.Fatal("unexpected error:", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if _, err := tx.Exec(`CREATE TABLE orders (id TEXT)`); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.WithTx(tx).Set(ctx, "app.name", "My New App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.Get(ctx, "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "My New App" {
+		t.Fatal("Write in the transaction of the caller MUST be committed with it, found:", value)
+	}
+
+	tx, err = db.BeginTx(ctx, nil)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.WithTx(tx).Set(ctx, "app.name", "Rolled Back"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err = store.Get(ctx, "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "My New App" {
+		t.Fatal("Write in the transaction of the caller MUST be rolled back with it, found:", value)
+	}
+
+	if err := store.WithTx(nil).Set(ctx, "app.name", "nil"); err == nil {
+		t.Fatal("Store bound to a nil transaction MUST fail")
+	}
+}
@@ -0,0 +1,258 @@
+package settingstore
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// GetInt is a shortcut method to get a value by key as an int, or a default if not found
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - int - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value is not an int, nil if no error
+func (st *store) GetInt(ctx context.Context, settingKey string, valueDefault int) (int, error) {
+	return getParsed(ctx, st, settingKey, valueDefault, "int", strconv.Atoi)
+}
+
+// GetInt64 is a shortcut method to get a value by key as an int64, or a default if not found
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - int64 - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value is not an int64, nil if no error
+func (st *store) GetInt64(ctx context.Context, settingKey string, valueDefault int64) (int64, error) {
+	return getParsed(ctx, st, settingKey, valueDefault, "int64", func(value string) (int64, error) {
+		return strconv.ParseInt(value, 10, 64)
+	})
+}
+
+// GetBool is a shortcut method to get a value by key as a bool, or a default if not found
+//
+// Accepts the values understood by strconv.ParseBool (1, t, true, 0, f, false, etc)
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - bool - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value is not a bool, nil if no error
+func (st *store) GetBool(ctx context.Context, settingKey string, valueDefault bool) (bool, error) {
+	return getParsed(ctx, st, settingKey, valueDefault, "bool", strconv.ParseBool)
+}
+
+// GetFloat64 is a shortcut method to get a value by key as a float64, or a default if not found
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - float64 - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value is not a float64, nil if no error
+func (st *store) GetFloat64(ctx context.Context, settingKey string, valueDefault float64) (float64, error) {
+	return getParsed(ctx, st, settingKey, valueDefault, "float64", func(value string) (float64, error) {
+		return strconv.ParseFloat(value, 64)
+	})
+}
+
+// GetDuration is a shortcut method to get a value by key as a duration, or a default if not found
+//
+// Accepts the values understood by time.ParseDuration (i.e. 300ms, 1h30m)
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - time.Duration - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value is not a duration, nil if no error
+func (st *store) GetDuration(ctx context.Context, settingKey string, valueDefault time.Duration) (time.Duration, error) {
+	return getParsed(ctx, st, settingKey, valueDefault, "duration", time.ParseDuration)
+}
+
+// GetTime is a shortcut method to get a value by key as a time, or a default if not found
+//
+// Accepts RFC 3339 values (i.e. 2006-01-02T15:04:05Z)
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - time.Time - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value is not a time, nil if no error
+func (st *store) GetTime(ctx context.Context, settingKey string, valueDefault time.Time) (time.Time, error) {
+	return getParsed(ctx, st, settingKey, valueDefault, "time", func(value string) (time.Time, error) {
+		return time.Parse(time.RFC3339Nano, value)
+	})
+}
+
+// GetStringSlice is a shortcut method to get a value by key as a string slice, or a default if not found
+//
+// Accepts JSON arrays of strings (i.e. ["a","b"]), as saved by SetStringSlice
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+//
+// Returns:
+// - []string - the value of the setting, or the default value if not found
+// - error - a *ParseError if the value is not a string slice, nil if no error
+func (st *store) GetStringSlice(ctx context.Context, settingKey string, valueDefault []string) ([]string, error) {
+	return getParsed(ctx, st, settingKey, valueDefault, "string slice", func(value string) ([]string, error) {
+		var slice []string
+		err := json.Unmarshal([]byte(value), &slice)
+		return slice, err
+	})
+}
+
+// SetInt is a shortcut method to save an int by key, use GetInt to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetInt(ctx context.Context, settingKey string, value int) error {
+	return st.Set(ctx, settingKey, strconv.Itoa(value))
+}
+
+// SetInt64 is a shortcut method to save an int64 by key, use GetInt64 to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetInt64(ctx context.Context, settingKey string, value int64) error {
+	return st.Set(ctx, settingKey, strconv.FormatInt(value, 10))
+}
+
+// SetBool is a shortcut method to save a bool by key, use GetBool to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetBool(ctx context.Context, settingKey string, value bool) error {
+	return st.Set(ctx, settingKey, strconv.FormatBool(value))
+}
+
+// SetFloat64 is a shortcut method to save a float64 by key, use GetFloat64 to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetFloat64(ctx context.Context, settingKey string, value float64) error {
+	return st.Set(ctx, settingKey, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// SetDuration is a shortcut method to save a duration by key, use GetDuration to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetDuration(ctx context.Context, settingKey string, value time.Duration) error {
+	return st.Set(ctx, settingKey, value.String())
+}
+
+// SetTime is a shortcut method to save a time by key in RFC 3339 format, use GetTime to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetTime(ctx context.Context, settingKey string, value time.Time) error {
+	return st.Set(ctx, settingKey, value.Format(time.RFC3339Nano))
+}
+
+// SetStringSlice is a shortcut method to save a string slice by key as JSON, use GetStringSlice to extract
+//
+// Parameters:
+// - ctx: the context
+// - settingKey: the key of the setting to save
+// - value: the value to save
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) SetStringSlice(ctx context.Context, settingKey string, value []string) error {
+	if value == nil {
+		value = []string{}
+	}
+
+	jsonValue, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	return st.Set(ctx, settingKey, string(jsonValue))
+}
+
+// getParsed gets the value of a setting by key, and parses it into the type
+//
+// Parameters:
+// - ctx: the context
+// - st: the store
+// - settingKey: the key of the setting to get
+// - valueDefault: the default value to return if the setting is not found
+// - typeName: the name of the type, used in the parse error
+// - parse: the function to parse the value
+//
+// Returns:
+// - T - the parsed value, or the default value if not found or not valid
+// - error - a *ParseError if the value cannot be parsed, nil if no error
+func getParsed[T any](ctx context.Context, st *store, settingKey string, valueDefault T, typeName string, parse func(string) (T, error)) (T, error) {
+	value, found, err := st.findValueByKey(ctx, settingKey)
+
+	if err != nil {
+		return valueDefault, err
+	}
+
+	if !found {
+		return valueDefault, nil
+	}
+
+	parsed, err := parse(value)
+
+	if err != nil {
+		return valueDefault, newParseError(settingKey, typeName, value, err, st.isSensitiveSettingKey(settingKey))
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,114 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStore_TypedGettersAndSetters(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	if err := store.SetInt(ctx, "int", 42); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetInt64(ctx, "int64", 9007199254740993); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetBool(ctx, "bool", true); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetFloat64(ctx, "float64", 0.25); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetDuration(ctx, "duration", 90*time.Second); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetTime(ctx, "time", now); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SetStringSlice(ctx, "slice", []string{"a", "b"}); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if v, err := store.GetInt(ctx, "int", 0); err != nil || v != 42 {
+		t.Fatal("GetInt failed:", v, err)
+	}
+
+	if v, err := store.GetInt64(ctx, "int64", 0); err != nil || v != 9007199254740993 {
+		t.Fatal("GetInt64 failed:", v, err)
+	}
+
+	if v, err := store.GetBool(ctx, "bool", false); err != nil || !v {
+		t.Fatal("GetBool failed:", v, err)
+	}
+
+	if v, err := store.GetFloat64(ctx, "float64", 0); err != nil || v != 0.25 {
+		t.Fatal("GetFloat64 failed:", v, err)
+	}
+
+	if v, err := store.GetDuration(ctx, "duration", 0); err != nil || v != 90*time.Second {
+		t.Fatal("GetDuration failed:", v, err)
+	}
+
+	if v, err := store.GetTime(ctx, "time", time.Time{}); err != nil || !v.Equal(now) {
+		t.Fatal("GetTime failed:", v, err)
+	}
+
+	if v, err := store.GetStringSlice(ctx, "slice", nil); err != nil || !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Fatal("GetStringSlice failed:", v, err)
+	}
+
+	if v, err := store.GetInt(ctx, "missing", 7); err != nil || v != 7 {
+		t.Fatal("GetInt MUST return the default value:", v, err)
+	}
+}
+
+func TestStore_TypedGetterParseError(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "server.port", "eighty"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.GetInt(ctx, "server.port", 80)
+
+	if err == nil {
+		t.Fatal("GetInt MUST fail for a non numeric value")
+	}
+
+	var parseError *ParseError
+
+	if !errors.As(err, &parseError) {
+		t.Fatal("error MUST be a *ParseError, found: ", err)
+	}
+
+	if parseError.Key != "server.port" || parseError.Type != "int" {
+		t.Fatalf("unexpected parse error: %+v", parseError)
+	}
+
+	if value != 80 {
+		t.Fatal("GetInt MUST return the default value on error, found: ", value)
+	}
+}
@@ -0,0 +1,461 @@
+package settingstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// conditionalWriteAttempts is the number of times a conditional write is
+// retried, when the setting is created or replaced concurrently
+const conditionalWriteAttempts = 3
+
+// upsertSupported returns true if the dialect saves a setting with a
+// single INSERT ... ON CONFLICT, or INSERT ... ON DUPLICATE KEY UPDATE
+func upsertSupported(dialect string) bool {
+	return lo.Contains([]string{sb.DIALECT_MYSQL, sb.DIALECT_POSTGRES, sb.DIALECT_SQLITE, "sqlite3"}, dialect)
+}
+
+// upsert saves the values by key, creating the settings which do not
+// exist and updating the ones which do, expired included
+//
+// The settings are saved with multi-row statements, chunked under the
+// parameter limit of the dialect, which rely on the unique index of the
+// live keys, so that concurrent saves of a new key never create
+// duplicates. On the dialects without native upserts, each setting is
+// found, and then created or updated
+//
+// Parameters:
+// - ctx: the context
+// - values: the values to save, by key
+// - expiresAt: the expiration date time of the saved settings
+//
+// Returns:
+// - error - nil if no error, error otherwise
+func (st *store) upsert(ctx context.Context, values map[string]string, expiresAt string) error {
+	keys := lo.Keys(values)
+	sort.Strings(keys)
+
+	if !upsertSupported(st.dbDriverName) {
+		return st.inTransaction(ctx, func(txStore *store) error {
+			for _, key := range keys {
+				if err := txStore.findThenWrite(ctx, key, values[key], expiresAt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	chunks := lo.Chunk(keys, bulkChunkSize(st.dbDriverName, len(st.settingTableColumns())))
+
+	return st.inTransaction(ctx, func(txStore *store) error {
+		for _, chunk := range chunks {
+			if err := txStore.upsertChunk(ctx, chunk, values, expiresAt); err != nil {
+				return err
+			}
+		}
+
+		txStore.afterCommit(func() {
+			for _, key := range keys {
+				txStore.cacheInvalidateKey(key)
+			}
+		})
+
+		return txStore.revisionBump(ctx)
+	})
+}
+
+// upsertChunk saves the values of the keys with a single statement,
+// in the transaction the store is bound to
+func (store *store) upsertChunk(ctx context.Context, keys []string, values map[string]string, expiresAt string) error {
+	settings := make([]SettingInterface, 0, len(keys))
+
+	for _, key := range keys {
+		sealedValue, err := store.sealValue(store.tenantID, store.namespacedKey(key), values[key])
+
+		if err != nil {
+			return err
+		}
+
+		settings = append(settings, NewSetting().
+			SetKey(key).
+			SetValue(sealedValue).
+			SetExpiresAt(expiresAt))
+	}
+
+	rows := lo.Map(settings, func(setting SettingInterface, _ int) any {
+		return lo.Assign(setting.Data(), map[string]string{
+			COLUMN_SETTING_KEY: store.namespacedKey(setting.GetKey()),
+			COLUMN_TENANT_ID:   store.tenantID,
+		})
+	})
+
+	sqlStr, params, errSql := store.upsertSql(rows...)
+
+	if errSql != nil {
+		return errSql
+	}
+
+	logParams := params
+
+	for _, setting := range settings {
+		logParams = store.redactParams(logParams, store.namespacedKey(setting.GetKey()), setting.GetValue())
+	}
+
+	store.logSql("upsert", sqlStr, logParams...)
+
+	changes, err := store.upsertChanges(ctx, settings)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := store.executeSql(ctx, sqlStr, params...); err != nil {
+		return err
+	}
+
+	return store.recordChanges(ctx, changes...)
+}
+
+// upsertSql returns the SQL of the insert of the rows, which updates the
+// existing row instead, when an inserted setting conflicts with the live
+// setting with the same key
+func (store *store) upsertSql(rows ...any) (string, []any, error) {
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Insert(store.settingTableName).
+		Prepared(true).
+		Rows(rows...).
+		ToSQL()
+
+	if errSql != nil {
+		return "", nil, errSql
+	}
+
+	return sqlStr + store.upsertConflictSql(), params, nil
+}
+
+// upsertConflictSql returns the clause updating the existing row, when
+// the inserted setting conflicts with the live setting with the same key.
+// It is written for the dialect, as goqu has no conflict target with the
+// predicate of a partial index
+func (store *store) upsertConflictSql() string {
+	quote := func(name string) string {
+		return quoteIdentifier(store.dbDriverName, name)
+	}
+
+	updatedColumns := []string{COLUMN_SETTING_VALUE, COLUMN_EXPIRES_AT, COLUMN_UPDATED_AT}
+
+	if store.dbDriverName == sb.DIALECT_MYSQL {
+		// the conflicting row is the one of the unique index
+		assignments := lo.Map(updatedColumns, func(column string, _ int) string {
+			return quote(column) + " = VALUES(" + quote(column) + ")"
+		})
+
+		assignments = append(assignments, quote(COLUMN_VERSION)+" = "+quote(COLUMN_VERSION)+" + 1")
+
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+	}
+
+	assignments := lo.Map(updatedColumns, func(column string, _ int) string {
+		return quote(column) + " = EXCLUDED." + quote(column)
+	})
+
+	assignments = append(assignments, quote(COLUMN_VERSION)+" = "+quote(store.settingTableName)+"."+quote(COLUMN_VERSION)+" + 1")
+
+	// the target must match the partial index, including its predicate
+	return " ON CONFLICT (" + quote(COLUMN_TENANT_ID) + ", " + quote(COLUMN_SETTING_KEY) + ") WHERE " + liveKeyPredicate +
+		" DO UPDATE SET " + strings.Join(assignments, ", ")
+}
+
+// upsertChanges returns the changes made by the upsert of the settings,
+// none if the changes are not recorded. A setting is updated if it has
+// a live row, and created otherwise
+func (store *store) upsertChanges(ctx context.Context, settings []SettingInterface) ([]recordedChange, error) {
+	if !store.changesRecorded() {
+		return []recordedChange{}, nil
+	}
+
+	fullKeys := lo.Map(settings, func(setting SettingInterface, _ int) string {
+		return store.namespacedKey(setting.GetKey())
+	})
+
+	rows, err := store.loadChangedRows(ctx,
+		goqu.C(COLUMN_SETTING_KEY).In(fullKeys),
+		goqu.C(COLUMN_SOFT_DELETED_AT).Gt(carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)),
+		store.tenantExpression())
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing := lo.KeyBy(rows, func(row map[string]string) string {
+		return row[COLUMN_SETTING_KEY]
+	})
+
+	changes := []recordedChange{}
+
+	for i, setting := range settings {
+		row, exists := existing[fullKeys[i]]
+
+		if !exists {
+			changes = append(changes, recordedChange{
+				settingID: setting.GetID(),
+				tenantID:  store.tenantID,
+				key:       fullKeys[i],
+				action:    HISTORY_ACTION_CREATE,
+				newValue:  setting.GetValue(),
+			})
+
+			continue
+		}
+
+		if store.sameValue(store.tenantID, fullKeys[i], row[COLUMN_SETTING_VALUE], setting.GetValue()) {
+			continue
+		}
+
+		changes = append(changes, recordedChange{
+			settingID: row[COLUMN_ID],
+			tenantID:  store.tenantID,
+			key:       fullKeys[i],
+			action:    HISTORY_ACTION_UPDATE,
+			oldValue:  row[COLUMN_SETTING_VALUE],
+			newValue:  setting.GetValue(),
+		})
+	}
+
+	return changes, nil
+}
+
+// findThenWrite saves the value by key, on the dialects without native
+// upserts. It finds the setting, and then creates or updates it
+func (store *store) findThenWrite(ctx context.Context, settingKey string, value string, expiresAt string) error {
+	// expired settings are included, so that the row is reused instead of duplicated
+	list, errList := store.settingList(ctx, SettingQuery().
+		SetKey(settingKey).
+		SetExpiredIncluded(true).
+		SetLimit(1))
+
+	if errList != nil {
+		return errList
+	}
+
+	if len(list) < 1 {
+		newSetting := NewSetting().
+			SetKey(settingKey).
+			SetValue(value).
+			SetExpiresAt(expiresAt)
+
+		return store.SettingCreate(ctx, newSetting)
+	}
+
+	setting := list[0]
+	setting.SetValue(value)
+	setting.SetExpiresAt(expiresAt)
+
+	return store.SettingUpdate(ctx, setting)
+}
+
+// insertIfAbsent creates the setting, unless a live setting with the same
+// key exists, expired included
+//
+// On the dialects with native upserts the setting is inserted with a
+// single statement ignoring the conflict with the unique index of the
+// live keys, so that of concurrent calls only one creates the setting.
+// On the others the setting is found, and then created
+//
+// Parameters:
+// - ctx: the context
+// - setting: the setting to create
+//
+// Returns:
+// - bool - true if the setting was created, false if it exists
+// - error - nil if no error, error otherwise
+func (st *store) insertIfAbsent(ctx context.Context, setting SettingInterface) (bool, error) {
+	if !upsertSupported(st.dbDriverName) {
+		inserted := false
+
+		err := st.inTransaction(ctx, func(txStore *store) error {
+			list, err := txStore.settingList(ctx, SettingQuery().
+				SetKey(setting.GetKey()).
+				SetExpiredIncluded(true).
+				SetLimit(1))
+
+			if err != nil || len(list) > 0 {
+				return err
+			}
+
+			inserted = true
+
+			return txStore.SettingCreate(ctx, setting)
+		})
+
+		return inserted, err
+	}
+
+	data := lo.Assign(setting.Data(), map[string]string{
+		COLUMN_SETTING_KEY: st.namespacedKey(setting.GetKey()),
+		COLUMN_TENANT_ID:   st.tenantID,
+	})
+
+	sealedValue, errSeal := st.sealValue(st.tenantID, data[COLUMN_SETTING_KEY], setting.GetValue())
+
+	if errSeal != nil {
+		return false, errSeal
+	}
+
+	data[COLUMN_SETTING_VALUE] = sealedValue
+
+	sqlStr, params, errSql := st.insertIgnoringConflictSql(goqu.Dialect(st.dbDriverName).
+		Insert(st.settingTableName).
+		Prepared(true).
+		Rows(data))
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	st.logSql("create", sqlStr, st.redactParams(params, data[COLUMN_SETTING_KEY], sealedValue)...)
+
+	inserted := false
+
+	err := st.inTransaction(ctx, func(txStore *store) error {
+		result, err := txStore.executeSql(ctx, sqlStr, params...)
+
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+
+		if err != nil || affected < 1 {
+			return err
+		}
+
+		inserted = true
+
+		errRecord := txStore.recordChanges(ctx, recordedChange{
+			settingID: setting.GetID(),
+			tenantID:  txStore.tenantID,
+			key:       txStore.namespacedKey(setting.GetKey()),
+			action:    HISTORY_ACTION_CREATE,
+			newValue:  sealedValue,
+		})
+
+		if errRecord != nil {
+			return errRecord
+		}
+
+		txStore.afterCommit(func() {
+			txStore.cacheInvalidateKey(setting.GetKey()) // the key may be cached as missing
+		})
+
+		return txStore.revisionBump(ctx)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return inserted, nil
+}
+
+// replaceExpired replaces the value of the expired setting, unless it was
+// replaced concurrently, and makes it never expire
+//
+// Returns:
+// - bool - true if the setting was replaced, false otherwise
+// - error - nil if no error, error otherwise
+func (store *store) replaceExpired(ctx context.Context, setting SettingInterface, value string) (bool, error) {
+	now := carbon.Now(carbon.UTC).ToDateTimeString(carbon.UTC)
+
+	setting.SetValue(value)
+	setting.SetExpiresAt(sb.MAX_DATETIME)
+
+	sealedValue, errSeal := store.sealValue(store.tenantID, store.namespacedKey(setting.GetKey()), value)
+
+	if errSeal != nil {
+		return false, errSeal
+	}
+
+	sqlStr, params, errSql := goqu.Dialect(store.dbDriverName).
+		Update(store.settingTableName).
+		Prepared(true).
+		Set(goqu.Record{
+			COLUMN_SETTING_VALUE: sealedValue,
+			COLUMN_EXPIRES_AT:    setting.GetExpiresAt(),
+			COLUMN_UPDATED_AT:    now,
+			COLUMN_VERSION:       versionIncrementExpression(),
+		}).
+		Where(goqu.C(COLUMN_ID).Eq(setting.GetID())).
+		Where(store.tenantExpression()).
+		Where(goqu.C(COLUMN_EXPIRES_AT).Lte(now)).
+		ToSQL()
+
+	if errSql != nil {
+		return false, errSql
+	}
+
+	store.logSql("update", sqlStr, store.redactParams(params, store.namespacedKey(setting.GetKey()), sealedValue)...)
+
+	change, err := store.updateChange(ctx, setting, lo.Assign(setting.DataChanged(), map[string]string{
+		COLUMN_SETTING_VALUE: sealedValue,
+	}))
+
+	if err != nil {
+		return false, err
+	}
+
+	result, err := store.executeSql(ctx, sqlStr, params...)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil || affected < 1 {
+		return false, err
+	}
+
+	if change != nil {
+		if err := store.recordChanges(ctx, *change); err != nil {
+			return false, err
+		}
+	}
+
+	store.afterCommit(func() {
+		store.cacheInvalidateKey(setting.GetKey())
+		store.cacheInvalidateID(setting.GetID())
+	})
+
+	return true, store.revisionBump(ctx)
+}
+
+// insertIgnoringConflictSql returns the SQL of the insert, which inserts
+// nothing if the row conflicts with a unique index of the table. On the
+// dialects without native upserts the insert fails then
+func (store *store) insertIgnoringConflictSql(insert *goqu.InsertDataset) (string, []any, error) {
+	if !upsertSupported(store.dbDriverName) {
+		return insert.ToSQL()
+	}
+
+	if store.dbDriverName != sb.DIALECT_MYSQL {
+		return insert.OnConflict(goqu.DoNothing()).ToSQL()
+	}
+
+	// MySQL has no DO NOTHING, an update to the same value changes no row
+	sqlStr, params, errSql := insert.
+		OnConflict(goqu.DoUpdate("", goqu.Record{COLUMN_ID: goqu.L("?", goqu.C(COLUMN_ID))})).
+		ToSQL()
+
+	// goqu adds IGNORE to any conflict, which would turn the
+	// errors of the insert into warnings
+	return strings.Replace(sqlStr, "INSERT IGNORE INTO", "INSERT INTO", 1), params, errSql
+}
@@ -0,0 +1,208 @@
+package settingstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gouniverse/sb"
+)
+
+func TestStoreSetUpsert(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	for _, value := range []string{"a", "b"} {
+		if err := store.Set(ctx, "app.name", value); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+
+	count := 0
+
+	if err := db.QueryRow(`SELECT COUNT(*) FROM setting`).Scan(&count); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if count != 1 {
+		t.Fatal("Saving an existing key MUST update its row, found rows:", count)
+	}
+
+	setting, err := store.SettingFindByKey(ctx, "app.name")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if setting.GetValue() != "b" || setting.GetVersion() != 2 {
+		t.Fatal("Setting MUST be updated, found:", setting.GetValue(), setting.GetVersion())
+	}
+
+	if err := store.SettingSoftDelete(ctx, setting); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "app.name", "c"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.Get(ctx, "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "c" {
+		t.Fatal("Soft deleted key MUST be saved as a new setting, found:", value)
+	}
+}
+
+func TestStoreKeyIndex(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "app.name", "My App"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.SettingCreate(ctx, NewSetting().SetKey("app.name")); err == nil {
+		t.Fatal("A second live setting with the same key MUST be rejected")
+	}
+
+	if err := store.ForTenant("acme").SettingCreate(ctx, NewSetting().SetKey("app.name")); err != nil {
+		t.Fatal("The same key of another tenant MUST be accepted, found:", err)
+	}
+}
+
+func TestStoreKeyIndexMigration(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	// a table with duplicates, saved by concurrent calls before the index
+	_, err = db.Exec(`CREATE TABLE setting (id TEXT PRIMARY KEY, tenant_id TEXT, setting_key TEXT,
+		setting_value TEXT, created_at DATETIME, updated_at DATETIME, expires_at DATETIME,
+		soft_deleted_at DATETIME, version INTEGER)`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO setting VALUES
+		('1', '', 'app.name', 'old', '2025-01-01 10:00:00', '2025-01-01 10:00:00', '9999-12-31 23:59:59', '9999-12-31 23:59:59', 1),
+		('2', '', 'app.name', 'new', '2025-01-01 10:00:00', '2025-01-02 10:00:00', '9999-12-31 23:59:59', '9999-12-31 23:59:59', 1),
+		('3', '', 'app.name', 'deleted', '2025-01-01 10:00:00', '2025-01-03 10:00:00', '9999-12-31 23:59:59', '2025-01-03 10:00:00', 1),
+		('4', 'acme', 'app.name', 'acme', '2025-01-01 10:00:00', '2025-01-01 10:00:00', '9999-12-31 23:59:59', '9999-12-31 23:59:59', 1)`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ids := []string{}
+
+	rows, err := db.Query(`SELECT id FROM setting ORDER BY id`)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	for rows.Next() {
+		id := ""
+
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	rows.Close() // releases the single connection
+
+	if len(ids) != 3 || ids[0] != "2" {
+		t.Fatal("Only the most recently updated duplicate MUST be kept, found:", ids)
+	}
+
+	if err := store.Set(context.Background(), "app.name", "newer"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	value, err := store.Get(context.Background(), "app.name", "")
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if value != "newer" {
+		t.Fatal("Kept setting MUST be updated, found:", value)
+	}
+}
+
+func TestUpsertSql(t *testing.T) {
+	expected := map[string][]string{
+		sb.DIALECT_POSTGRES: {
+			`INSERT INTO "setting" ("id", "setting_key") VALUES ($1, $2) ON CONFLICT ("tenant_id", "setting_key") WHERE soft_deleted_at = '9999-12-31 23:59:59' DO UPDATE SET "setting_value" = EXCLUDED."setting_value", "expires_at" = EXCLUDED."expires_at", "updated_at" = EXCLUDED."updated_at", "version" = "setting"."version" + 1`,
+			`CREATE UNIQUE INDEX "setting_key_unique" ON "setting" ("tenant_id", "setting_key") WHERE soft_deleted_at = '9999-12-31 23:59:59'`,
+		},
+		sb.DIALECT_MYSQL: {
+			"INSERT INTO `setting` (`id`, `setting_key`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `setting_value` = VALUES(`setting_value`), `expires_at` = VALUES(`expires_at`), `updated_at` = VALUES(`updated_at`), `version` = `version` + 1",
+			"CREATE UNIQUE INDEX `setting_key_unique` ON `setting` (`tenant_id`, `setting_key`, `soft_deleted_at`)",
+		},
+	}
+
+	for dialect, sqls := range expected {
+		st := &store{dbDriverName: dialect, settingTableName: "setting"}
+
+		sqlStr, params, err := st.upsertSql(map[string]string{COLUMN_ID: "1", COLUMN_SETTING_KEY: "app.name"})
+
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		if sqlStr != sqls[0] {
+			t.Fatalf("Upsert of %s MUST be %q, found: %q", dialect, sqls[0], sqlStr)
+		}
+
+		if len(params) != 2 {
+			t.Fatalf("Upsert of %s MUST have the values of the row as parameters, found: %v", dialect, params)
+		}
+
+		if sqlStr := st.sqlCreateKeyIndex(); sqlStr != sqls[1] {
+			t.Fatalf("Key index of %s MUST be %q, found: %q", dialect, sqls[1], sqlStr)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dromara/carbon/v2"
+	"github.com/gouniverse/sb"
+	"github.com/samber/lo"
+)
+
+// SettingChange describes a change of a setting watched by a Watcher
+type SettingChange struct {
+	// Key is the key of the setting
+	Key string
+
+	// OldValue is the value before the change, empty if OldFound is false
+	OldValue string
+
+	// OldFound is false if the setting did not exist before the change
+	OldFound bool
+
+	// NewValue is the value after the change, empty if NewFound is false
+	NewValue string
+
+	// NewFound is false if the setting was deleted or has expired
+	NewFound bool
+}
+
+// WatcherOptions configures a Watcher
+type WatcherOptions struct {
+	// Interval is how often the watched settings are polled
+	// for changes. Defaults to 10 seconds
+	Interval time.Duration
+
+	// OnError is called with the errors of the background polls,
+	// i.e. a failed query or a value which cannot be bound.
+	// Optional, the errors are dropped if not set
+	OnError func(err error)
+}
+
+// Watcher keeps a struct bound to the settings up to date
+//
+// The struct is rebound into a new snapshot whenever a watched setting
+// changes. The snapshots are swapped atomically, so Current is lock-free
+// and may be called from any goroutine. A snapshot is never modified once
+// published, and must not be modified by its readers either
+type Watcher[T any] struct {
+	store    StoreInterface
+	interval time.Duration
+	onError  func(err error)
+	keys     []string
+	snapshot atomic.Pointer[T]
+	done     chan struct{}
+
+	callbacksMutex sync.Mutex
+	callbacks      []func(changes []SettingChange, previous *T, current *T)
+
+	// the poll state, guarded by pollMutex
+	pollMutex sync.Mutex
+	values    map[string]string
+	count     int64
+	updatedAt string
+	seen      map[string]string
+}
+
+// Watch binds a new T from the store, and keeps it up to date
+// by polling the store until the context is done
+//
+// The fields of T are mapped to the settings with struct tags, the same
+// way as Bind. A poll first looks for rows updated since the last poll,
+// ordered by updated_at, and for a changed count of the live settings.
+// Only if it finds any, the watched settings are reloaded and rebound.
+//
+// Parameters:
+// - ctx: the context, cancel it to stop the watcher
+// - store: the store to load the settings from
+// - options: the watcher options
+//
+// Returns:
+// - *Watcher[T] - the watcher
+// - error - a *BindError if the initial bind fails, nil if no error
+func Watch[T any](ctx context.Context, store StoreInterface, options WatcherOptions) (*Watcher[T], error) {
+	if store == nil {
+		return nil, errors.New("settingstore > watch. store cannot be nil")
+	}
+
+	if options.Interval < 0 {
+		return nil, errors.New("settingstore > watch. interval cannot be negative")
+	}
+
+	if options.Interval == 0 {
+		options.Interval = 10 * time.Second
+	}
+
+	fields, err := bindFields(new(T))
+
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := &Watcher[T]{
+		store:    store,
+		interval: options.Interval,
+		onError:  options.OnError,
+		done:     make(chan struct{}),
+		values:   map[string]string{},
+		seen:     map[string]string{},
+		keys: lo.Uniq(lo.Map(fields, func(field boundField, _ int) string {
+			return field.key
+		})),
+	}
+
+	marker, err := watcher.pollMarker(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values, snapshot, err := watcher.load(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	watcher.commitMarker(marker)
+	watcher.values = values
+	watcher.snapshot.Store(snapshot)
+
+	go watcher.run(ctx)
+
+	return watcher, nil
+}
+
+// Current returns the current snapshot of the bound struct
+//
+// Returns:
+// - *T - the current snapshot, which must not be modified
+func (w *Watcher[T]) Current() *T {
+	return w.snapshot.Load()
+}
+
+// OnChange registers a callback, called after a new snapshot is swapped in
+//
+// The callbacks are called in the order they were registered, from the
+// goroutine which polled the changes. They should return quickly, or the
+// next poll is delayed
+//
+// Parameters:
+// - callback: the function called with the changed settings, sorted by key,
+// and the previous and the current snapshots
+func (w *Watcher[T]) OnChange(callback func(changes []SettingChange, previous *T, current *T)) {
+	w.callbacksMutex.Lock()
+	defer w.callbacksMutex.Unlock()
+
+	w.callbacks = append(w.callbacks, callback)
+}
+
+// Done returns a channel, which is closed once the watcher has stopped
+func (w *Watcher[T]) Done() <-chan struct{} {
+	return w.done
+}
+
+// Refresh polls the store for changes immediately, without waiting
+// for the interval
+//
+// If a watched setting has changed, a new snapshot is swapped in and the
+// callbacks are called before Refresh returns. If the changed settings
+// cannot be bound, the current snapshot is kept
+//
+// Parameters:
+// - ctx: the context
+//
+// Returns:
+// - []SettingChange - the changed settings, sorted by key, empty if none
+// - error - a *BindError if the changed settings cannot be bound, nil if no error
+func (w *Watcher[T]) Refresh(ctx context.Context) ([]SettingChange, error) {
+	w.pollMutex.Lock()
+	defer w.pollMutex.Unlock()
+
+	marker, err := w.pollMarker(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !marker.changed {
+		return []SettingChange{}, nil
+	}
+
+	values, snapshot, err := w.load(ctx)
+
+	var bindError *BindError
+
+	if errors.As(err, &bindError) {
+		// retrying the same values cannot succeed, wait for the next change
+		w.commitMarker(marker)
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.commitMarker(marker)
+
+	changes := settingChanges(w.keys, w.values, values)
+
+	if len(changes) < 1 {
+		return changes, nil
+	}
+
+	w.values = values
+	previous := w.snapshot.Swap(snapshot)
+
+	w.callbacksMutex.Lock()
+	callbacks := append([]func(changes []SettingChange, previous *T, current *T){}, w.callbacks...)
+	w.callbacksMutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback(changes, previous, snapshot)
+	}
+
+	return changes, nil
+}
+
+// watcherMarker is the result of looking for changes since the last poll
+type watcherMarker struct {
+	changed   bool
+	count     int64
+	updatedAt string
+	seen      map[string]string
+}
+
+// pollMarker looks for the rows updated since the last poll, and for
+// a changed count of the live settings, which reveals the hard deleted
+// and the expired ones. The first poll scans only the rows updated in
+// the second of the latest update
+func (w *Watcher[T]) pollMarker(ctx context.Context) (watcherMarker, error) {
+	marker := watcherMarker{
+		updatedAt: w.updatedAt,
+		seen:      map[string]string{},
+	}
+
+	if len(w.keys) < 1 {
+		return marker, nil
+	}
+
+	updatedAtGte := w.updatedAt
+
+	if updatedAtGte == "" {
+		latest, err := listSettings(ctx, w.store, SettingQuery().
+			SetKeyIn(w.keys).
+			SetSoftDeletedIncluded(true).
+			SetExpiredIncluded(true).
+			SetOrderBy(COLUMN_UPDATED_AT).
+			SetSortOrder(sb.DESC).
+			SetLimit(1))
+
+		if err != nil {
+			return marker, err
+		}
+
+		if len(latest) > 0 {
+			updatedAtGte = latest[0].GetUpdatedAtCarbon().ToDateTimeString(carbon.UTC)
+		}
+	}
+
+	query := SettingQuery().
+		SetKeyIn(w.keys).
+		SetSoftDeletedIncluded(true).
+		SetExpiredIncluded(true).
+		SetOrderBy(COLUMN_UPDATED_AT).
+		SetSortOrder(sb.ASC)
+
+	if updatedAtGte != "" {
+		query.SetUpdatedAtGte(updatedAtGte)
+	}
+
+	settings, err := listSettings(ctx, w.store, query)
+
+	if err != nil {
+		return marker, err
+	}
+
+	for _, setting := range settings {
+		updatedAt := setting.GetUpdatedAtCarbon().ToDateTimeString(carbon.UTC)
+		fingerprint := strings.Join([]string{
+			updatedAt,
+			setting.GetValue(),
+			setting.GetExpiresAt(),
+			setting.GetSoftDeletedAt(),
+		}, "\x00")
+
+		// the rows updated in the same second as the last poll are
+		// returned again, and only count if they changed since
+		if updatedAt != w.updatedAt || w.seen[setting.GetID()] != fingerprint {
+			marker.changed = true
+		}
+
+		if updatedAt > marker.updatedAt {
+			marker.updatedAt = updatedAt
+			marker.seen = map[string]string{}
+		}
+
+		if updatedAt == marker.updatedAt {
+			marker.seen[setting.GetID()] = fingerprint
+		}
+	}
+
+	count, err := w.store.SettingCount(ctx, SettingQuery().SetKeyIn(w.keys))
+
+	if err != nil {
+		return marker, err
+	}
+
+	marker.count = count
+
+	if count != w.count {
+		marker.changed = true
+	}
+
+	return marker, nil
+}
+
+// commitMarker records the marker as the state of the last poll
+func (w *Watcher[T]) commitMarker(marker watcherMarker) {
+	w.count = marker.count
+	w.updatedAt = marker.updatedAt
+	w.seen = marker.seen
+}
+
+// load loads the watched settings, and binds them into a new snapshot
+func (w *Watcher[T]) load(ctx context.Context) (map[string]string, *T, error) {
+	snapshot := new(T)
+
+	fields, err := bindFields(snapshot)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := bindLoadValues(ctx, w.store, fields)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := bindApply(w.store, fields, values); err != nil {
+		return nil, nil, err
+	}
+
+	return values, snapshot, nil
+}
+
+// run polls the store at the interval, until the context is done
+func (w *Watcher[T]) run(ctx context.Context) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := w.Refresh(ctx)
+
+			if err != nil && ctx.Err() == nil && w.onError != nil {
+				w.onError(err)
+			}
+		}
+	}
+}
+
+// settingChanges returns the changes between the old and the new values
+// of the keys, sorted by key
+func settingChanges(keys []string, oldValues map[string]string, newValues map[string]string) []SettingChange {
+	changes := []SettingChange{}
+
+	for _, key := range keys {
+		oldValue, oldFound := oldValues[key]
+		newValue, newFound := newValues[key]
+
+		if oldFound == newFound && oldValue == newValue {
+			continue
+		}
+
+		changes = append(changes, SettingChange{
+			Key:      key,
+			OldValue: oldValue,
+			OldFound: oldFound,
+			NewValue: newValue,
+			NewFound: newFound,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
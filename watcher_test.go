@@ -0,0 +1,270 @@
+package settingstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type watcherTestConfig struct {
+	Host string `setting:"server.host" default:"localhost"`
+	Port int    `setting:"server.port" default:"8080"`
+}
+
+func TestWatcher(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := store.Set(ctx, "server.port", "9000"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	watcher, err := Watch[watcherTestConfig](ctx, store, WatcherOptions{
+		Interval: 10 * time.Millisecond,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if watcher.Current().Host != "localhost" || watcher.Current().Port != 9000 {
+		t.Fatalf("unexpected initial snapshot: %+v", watcher.Current())
+	}
+
+	changed := make(chan []SettingChange, 10)
+
+	watcher.OnChange(func(changes []SettingChange, previous *watcherTestConfig, current *watcherTestConfig) {
+		if previous.Port != 9000 || current.Port != 9001 {
+			t.Errorf("unexpected snapshots: %+v, %+v", previous, current)
+		}
+
+		changed <- changes
+	})
+
+	if err := store.Set(ctx, "server.port", "9001"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	select {
+	case changes := <-changed:
+		if len(changes) != 1 {
+			t.Fatalf("Changes MUST be 1, found: %d", len(changes))
+		}
+
+		change := changes[0]
+
+		if change.Key != "server.port" || change.OldValue != "9000" || change.NewValue != "9001" || !change.OldFound || !change.NewFound {
+			t.Fatalf("unexpected change: %+v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnChange MUST be called after the setting changes")
+	}
+
+	if watcher.Current().Port != 9001 {
+		t.Fatal("Current MUST return the new snapshot, found port:", watcher.Current().Port)
+	}
+
+	cancel()
+
+	select {
+	case <-watcher.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watcher MUST stop when the context is cancelled")
+	}
+}
+
+func TestWatcherRefresh(t *testing.T) {
+	store, err := initStore(":memory:")
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher, err := Watch[watcherTestConfig](ctx, store, WatcherOptions{
+		Interval: time.Hour,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	changes, err := watcher.Refresh(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(changes) != 0 {
+		t.Fatal("Changes MUST be empty, when nothing changed, found:", changes)
+	}
+
+	if err := store.Set(ctx, "server.port", "invalid"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	_, err = watcher.Refresh(ctx)
+
+	var bindError *BindError
+
+	if !errors.As(err, &bindError) {
+		t.Fatal("Refresh MUST return a bind error for an invalid value, found:", err)
+	}
+
+	if watcher.Current().Port != 8080 {
+		t.Fatal("Current MUST keep the last valid snapshot, found port:", watcher.Current().Port)
+	}
+
+	if err := store.Set(ctx, "server.port", "9000"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := store.Set(ctx, "server.host", "example.com"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	changes, err = watcher.Refresh(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(changes) != 2 || changes[0].Key != "server.host" || changes[0].OldFound || changes[1].Key != "server.port" {
+		t.Fatal("Changes MUST list the new host and port, found:", changes)
+	}
+
+	if err := store.Delete(ctx, "server.host"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	changes, err = watcher.Refresh(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(changes) != 1 || changes[0].NewFound {
+		t.Fatal("Changes MUST list the deleted host, found:", changes)
+	}
+
+	if watcher.Current().Host != "localhost" {
+		t.Fatal("Current MUST fall back to the default host, found:", watcher.Current().Host)
+	}
+}
+
+// listCountingStore counts the rows listed by the polls of a watcher,
+// which are the only lists including the soft deleted settings
+type listCountingStore struct {
+	StoreInterface
+	polledRows int
+}
+
+func (store *listCountingStore) SettingList(ctx context.Context, query SettingQueryInterface) ([]SettingInterface, error) {
+	list, err := store.StoreInterface.SettingList(ctx, query)
+
+	if query.SoftDeletedIncluded() {
+		store.polledRows += len(list)
+	}
+
+	return list, err
+}
+
+func TestWatcherFirstPoll(t *testing.T) {
+	db, err := initDB(":memory:")
+
+	if err != nil {
+		t.Fatal("Database could not be created: ", err.Error())
+	}
+
+	db.SetMaxOpenConns(1) // the in-memory database lives in a single connection
+
+	store, err := NewStore(NewStoreOptions{
+		DB:                 db,
+		SettingTableName:   "setting",
+		AutomigrateEnabled: true,
+	})
+
+	if err != nil {
+		t.Fatal("Store could not be created: ", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type config struct {
+		Host  string `setting:"server.host"`
+		Port  int    `setting:"server.port"`
+		Debug bool   `setting:"server.debug"`
+	}
+
+	err = store.SetMany(ctx, map[string]string{"server.host": "example.com", "server.port": "9000", "server.debug": "true"})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the host and the port were updated long before the debug flag
+	if _, err := db.Exec("UPDATE setting SET updated_at = '2020-01-01 00:00:00' WHERE setting_key <> 'server.debug'"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	countingStore := &listCountingStore{StoreInterface: store}
+
+	watcher, err := Watch[config](ctx, countingStore, WatcherOptions{
+		Interval: time.Hour,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// the latest update, and the rows updated in its second
+	if countingStore.polledRows != 2 {
+		t.Fatal("First poll MUST scan only the latest rows, scanned:", countingStore.polledRows)
+	}
+
+	changes, err := watcher.Refresh(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(changes) != 0 {
+		t.Fatal("Changes MUST be empty, when nothing changed, found:", changes)
+	}
+
+	if err := store.Set(ctx, "server.host", "example.org"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	changes, err = watcher.Refresh(ctx)
+
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if len(changes) != 1 || changes[0].NewValue != "example.org" {
+		t.Fatal("Changes MUST list the new host, found:", changes)
+	}
+}